@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/gocraft/dbr/v2"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -23,7 +24,7 @@ func ConfigureServer() *echo.Echo {
 	e := echo.New()
 	e.Logger.SetLevel(log.INFO)
 	e.Use(middleware.Recover())
-	e.Use(middleware.Logger())
+	e.Use(myMiddleware.Logger())
 	e.Use(middleware.RequestID())
 	e.Use(middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(30)))
 	return e
@@ -60,6 +61,27 @@ func GracefullyStart(e *echo.Echo) {
 	}
 }
 
+// createPasswordResetSender has no default sender, so a deployment that did not choose one fails at startup.
+func createPasswordResetSender() (service.PasswordResetSender, error) {
+	switch sender := config.GetPasswordResetSender(); sender {
+	case service.PASSWORD_RESET_SENDER_SMTP:
+		return service.CreateSmtpPasswordResetSender(
+			config.GetSmtpHost(),
+			config.GetSmtpPort(),
+			config.GetSmtpUsername(),
+			config.GetSmtpPassword(),
+			config.GetSmtpFrom(),
+			config.GetPasswordResetUrl(),
+		)
+	case service.PASSWORD_RESET_SENDER_LOG:
+		return service.CreateLogPasswordResetSender(), nil
+	case "":
+		return nil, errors.New("password reset sender is not configured, set MARKETLIST_AUTH_RESET_SENDER")
+	default:
+		return nil, fmt.Errorf("unknown password reset sender %q", sender)
+	}
+}
+
 func main() {
 	err := config.Init()
 	if err != nil {
@@ -68,7 +90,6 @@ func main() {
 	e := ConfigureServer()
 
 	e.Use(myMiddleware.InjectLogger)
-	e.Use(myMiddleware.InjectUserId)
 
 	db, err := dbr.Open("postgres", config.GetDatabaseDSN(), nil)
 	if err != nil {
@@ -78,34 +99,146 @@ func main() {
 	db.SetMaxOpenConns(20)
 
 	userRepository := repository.CreateUserRepository(db)
-	userService := service.CreateUserService(userRepository)
+	passwordResetSender, err := createPasswordResetSender()
+	if err != nil {
+		panic(err)
+	}
+	userService := service.CreateUserService(
+		userRepository,
+		passwordResetSender,
+		config.GetPasswordCost(),
+		config.GetPasswordResetExpiration(),
+	)
+	userController := controller.CreateUserController(userService)
+
+	authService, err := service.CreateAuthService(userService, config.GetAuthSecret(), config.GetAuthTokenExpiration())
+	if err != nil {
+		panic(err)
+	}
+	authController := controller.CreateAuthController(authService)
+
+	publicRoutes := append(authController.PublicRoutes(), userController.PublicRoutes()...)
+	e.Use(myMiddleware.InjectUserId(authService, publicRoutes...))
 
 	productRepository := repository.CreateProductRepository(db)
 	productService := service.CreateProductService(productRepository)
 	productController := controller.CreateProductController(productService)
 
+	priceRepository := repository.CreatePriceRepository(db)
+	priceService := service.CreatePriceService(priceRepository, productService)
+	priceController := controller.CreatePriceController(priceService)
+
 	marketRepository := repository.CreateMarketRepository(db)
 	marketService := service.CreateMarketService(marketRepository)
 	marketController := controller.CreateMarketController(marketService)
 
+	marketLayoutRepository := repository.CreateMarketLayoutRepository(db)
+	marketLayoutService := service.CreateMarketLayoutService(marketLayoutRepository, marketService, userService)
+	marketLayoutController := controller.CreateMarketLayoutController(marketLayoutService)
+
+	categoryRepository := repository.CreateCategoryRepository(db)
+	categoryService := service.CreateCategoryService(categoryRepository, userService)
+	categoryController := controller.CreateCategoryController(categoryService)
+
 	purchaseRepository := repository.CreatePurchaseRepository(db)
-	purchaseService := service.CreatePurchaseService(purchaseRepository, productService, userService)
+	purchaseEventBroker := service.CreateMemoryPurchaseEventBroker()
+
+	alertRepository := repository.CreateAlertRepository(db)
+	alertNotifier := service.CreateLogAlertNotifier()
+	alertService := service.CreateAlertService(alertRepository, priceRepository, purchaseRepository, productService, alertNotifier)
+	alertController := controller.CreateAlertController(alertService)
+
+	purchaseService := service.CreatePurchaseService(purchaseRepository, productService, userService, purchaseEventBroker, categoryService, marketLayoutService, priceService, alertService)
 	purchaseController := controller.CreatePurchaseController(purchaseService)
 
+	marketRecommendationService := service.CreateMarketRecommendationService(purchaseService, priceService, marketService)
+	marketRecommendationController := controller.CreateMarketRecommendationController(marketRecommendationService)
+
+	tagRepository := repository.CreateTagRepository(db)
+	tagService := service.CreateTagService(tagRepository, purchaseService)
+	tagController := controller.CreateTagController(tagService)
+
+	inviteRepository := repository.CreateInviteRepository(db)
+	inviteService := service.CreateInviteService(inviteRepository, purchaseService, userService)
+	inviteController := controller.CreateInviteController(inviteService)
+
+	syncRepository := repository.CreateSyncRepository(db)
+	syncService := service.CreateSyncService(syncRepository, purchaseRepository, purchaseService, purchaseEventBroker, alertService, config.GetSyncRetention())
+	syncController := controller.CreateSyncController(syncService)
+
+	reportRepository := repository.CreateReportRepository(db)
+	reportService := service.CreateReportService(reportRepository, userService)
+	reportController := controller.CreateReportController(reportService)
+
+	err = authController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
+	err = userController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
 	err = productController.Register(e)
 	if err != nil {
 		panic(err)
 	}
 
+	err = priceController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
 	err = marketController.Register(e)
 	if err != nil {
 		panic(err)
 	}
 
+	err = marketLayoutController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
+	err = categoryController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
 	err = purchaseController.Register(e)
 	if err != nil {
 		panic(err)
 	}
 
+	err = marketRecommendationController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
+	err = alertController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
+	err = tagController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
+	err = inviteController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
+	err = syncController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
+	err = reportController.Register(e)
+	if err != nil {
+		panic(err)
+	}
+
 	GracefullyStart(e)
 }
@@ -0,0 +1,3 @@
+\c market_list;
+
+CREATE UNIQUE INDEX MARKET_USER_EMAIL_UNIQUE ON MARKET_USER (LOWER(EMAIL));
@@ -0,0 +1,11 @@
+\c market_list;
+
+CREATE TABLE PASSWORD_RESET_TOKEN
+(
+    ID         BIGSERIAL PRIMARY KEY,
+    USER_ID    BIGINT REFERENCES MARKET_USER (ID) NOT NULL,
+    TOKEN_HASH VARCHAR(64)                        NOT NULL UNIQUE,
+    EXPIRES_AT TIMESTAMP                          NOT NULL,
+    USED_AT    TIMESTAMP,
+    CREATED_AT TIMESTAMP DEFAULT NOW()
+);
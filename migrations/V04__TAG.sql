@@ -0,0 +1,11 @@
+\c market_list;
+
+CREATE UNIQUE INDEX TAG_USER_NAME_UNIQUE ON TAG (USER_ID, LOWER(NAME));
+
+ALTER TABLE TAG_PURCHASE
+    DROP CONSTRAINT TAG_PURCHASE_PURCHASE_ID_FKEY,
+    ADD CONSTRAINT TAG_PURCHASE_PURCHASE_ID_FKEY FOREIGN KEY (PURCHASE_ID) REFERENCES PURCHASE (ID) ON DELETE CASCADE;
+
+ALTER TABLE TAG_PURCHASE
+    DROP CONSTRAINT TAG_PURCHASE_TAG_ID_FKEY,
+    ADD CONSTRAINT TAG_PURCHASE_TAG_ID_FKEY FOREIGN KEY (TAG_ID) REFERENCES TAG (ID) ON DELETE CASCADE;
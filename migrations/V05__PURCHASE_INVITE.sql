@@ -0,0 +1,18 @@
+\c market_list;
+
+ALTER TABLE PURCHASE_USER
+    DROP CONSTRAINT PURCHASE_USER_PURCHASE_ID_FKEY,
+    ADD CONSTRAINT PURCHASE_USER_PURCHASE_ID_FKEY FOREIGN KEY (PURCHASE_ID) REFERENCES PURCHASE (ID) ON DELETE CASCADE;
+
+CREATE TABLE PURCHASE_INVITE
+(
+    ID          BIGSERIAL PRIMARY KEY,
+    PURCHASE_ID BIGINT REFERENCES PURCHASE (ID) ON DELETE CASCADE NOT NULL,
+    INVITER_ID  BIGINT REFERENCES MARKET_USER (ID)                NOT NULL,
+    EMAIL       VARCHAR(300)                                      NOT NULL,
+    STATUS      VARCHAR(20) DEFAULT 'PENDING'                     NOT NULL,
+    CREATED_AT  TIMESTAMP   DEFAULT NOW(),
+    UPDATED_AT  TIMESTAMP   DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX PURCHASE_INVITE_PENDING_UNIQUE ON PURCHASE_INVITE (PURCHASE_ID, LOWER(EMAIL)) WHERE STATUS = 'PENDING';
@@ -0,0 +1,10 @@
+\c market_list;
+
+ALTER TABLE PURCHASE_USER
+    ADD COLUMN ROLE VARCHAR(20) NOT NULL DEFAULT 'OWNER';
+
+ALTER TABLE PURCHASE_USER
+    ALTER COLUMN ROLE SET DEFAULT 'EDITOR';
+
+ALTER TABLE PURCHASE_INVITE
+    ADD COLUMN ROLE VARCHAR(20) NOT NULL DEFAULT 'EDITOR';
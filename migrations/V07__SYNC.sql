@@ -0,0 +1,126 @@
+\c market_list;
+
+ALTER TABLE PURCHASE
+    ADD COLUMN UPDATED_AT             TIMESTAMP DEFAULT NOW(),
+    ADD COLUMN NAME_UPDATED_AT        TIMESTAMP DEFAULT NOW(),
+    ADD COLUMN MARKET_ID_UPDATED_AT   TIMESTAMP DEFAULT NOW(),
+    ADD COLUMN IS_FAVORITE_UPDATED_AT TIMESTAMP DEFAULT NOW();
+
+ALTER TABLE PURCHASE_ITEM
+    ADD COLUMN UPDATED_AT           TIMESTAMP DEFAULT NOW(),
+    ADD COLUMN PURCHASED_UPDATED_AT TIMESTAMP DEFAULT NOW(),
+    ADD COLUMN QUANTITY_UPDATED_AT  TIMESTAMP DEFAULT NOW(),
+    ADD COLUMN PRICE_UPDATED_AT     TIMESTAMP DEFAULT NOW();
+
+-- Field timestamps are bumped on every change, unless the statement sets them explicitly (sync mutations do).
+CREATE FUNCTION TOUCH_PURCHASE() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.UPDATED_AT = NOW();
+    IF NEW.NAME IS DISTINCT FROM OLD.NAME AND NEW.NAME_UPDATED_AT IS NOT DISTINCT FROM OLD.NAME_UPDATED_AT THEN
+        NEW.NAME_UPDATED_AT = NOW();
+    END IF;
+    IF NEW.MARKET_ID IS DISTINCT FROM OLD.MARKET_ID AND NEW.MARKET_ID_UPDATED_AT IS NOT DISTINCT FROM OLD.MARKET_ID_UPDATED_AT THEN
+        NEW.MARKET_ID_UPDATED_AT = NOW();
+    END IF;
+    IF NEW.IS_FAVORITE IS DISTINCT FROM OLD.IS_FAVORITE AND NEW.IS_FAVORITE_UPDATED_AT IS NOT DISTINCT FROM OLD.IS_FAVORITE_UPDATED_AT THEN
+        NEW.IS_FAVORITE_UPDATED_AT = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER PURCHASE_TOUCH BEFORE UPDATE ON PURCHASE
+    FOR EACH ROW EXECUTE FUNCTION TOUCH_PURCHASE();
+
+CREATE FUNCTION TOUCH_PURCHASE_ITEM() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.UPDATED_AT = NOW();
+    IF NEW.PURCHASED IS DISTINCT FROM OLD.PURCHASED AND NEW.PURCHASED_UPDATED_AT IS NOT DISTINCT FROM OLD.PURCHASED_UPDATED_AT THEN
+        NEW.PURCHASED_UPDATED_AT = NOW();
+    END IF;
+    IF NEW.QUANTITY IS DISTINCT FROM OLD.QUANTITY AND NEW.QUANTITY_UPDATED_AT IS NOT DISTINCT FROM OLD.QUANTITY_UPDATED_AT THEN
+        NEW.QUANTITY_UPDATED_AT = NOW();
+    END IF;
+    IF NEW.PRICE IS DISTINCT FROM OLD.PRICE AND NEW.PRICE_UPDATED_AT IS NOT DISTINCT FROM OLD.PRICE_UPDATED_AT THEN
+        NEW.PRICE_UPDATED_AT = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER PURCHASE_ITEM_TOUCH BEFORE UPDATE ON PURCHASE_ITEM
+    FOR EACH ROW EXECUTE FUNCTION TOUCH_PURCHASE_ITEM();
+
+-- TX_ID lets the sync feed only advance past transactions that are already committed.
+CREATE TABLE CHANGE_LOG
+(
+    ID          BIGSERIAL PRIMARY KEY,
+    TX_ID       BIGINT      DEFAULT TXID_CURRENT() NOT NULL,
+    ENTITY      VARCHAR(30)                        NOT NULL,
+    ENTITY_ID   BIGINT                             NOT NULL,
+    PURCHASE_ID BIGINT                             NOT NULL,
+    USER_ID     BIGINT,
+    OPERATION   VARCHAR(10)                        NOT NULL,
+    CHANGED_AT  TIMESTAMP   DEFAULT NOW()
+);
+
+CREATE INDEX CHANGE_LOG_TX_ID ON CHANGE_LOG (TX_ID);
+
+CREATE FUNCTION LOG_PURCHASE_CHANGE() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO CHANGE_LOG(ENTITY, ENTITY_ID, PURCHASE_ID, OPERATION) VALUES ('PURCHASE', OLD.ID, OLD.ID, 'DELETE');
+        RETURN OLD;
+    END IF;
+    INSERT INTO CHANGE_LOG(ENTITY, ENTITY_ID, PURCHASE_ID, OPERATION) VALUES ('PURCHASE', NEW.ID, NEW.ID, 'UPSERT');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER PURCHASE_CHANGE_LOG AFTER INSERT OR UPDATE OR DELETE ON PURCHASE
+    FOR EACH ROW EXECUTE FUNCTION LOG_PURCHASE_CHANGE();
+
+CREATE FUNCTION LOG_PURCHASE_ITEM_CHANGE() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO CHANGE_LOG(ENTITY, ENTITY_ID, PURCHASE_ID, OPERATION) VALUES ('PURCHASE_ITEM', OLD.ID, OLD.PURCHASE_ID, 'DELETE');
+        RETURN OLD;
+    END IF;
+    INSERT INTO CHANGE_LOG(ENTITY, ENTITY_ID, PURCHASE_ID, OPERATION) VALUES ('PURCHASE_ITEM', NEW.ID, NEW.PURCHASE_ID, 'UPSERT');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER PURCHASE_ITEM_CHANGE_LOG AFTER INSERT OR UPDATE OR DELETE ON PURCHASE_ITEM
+    FOR EACH ROW EXECUTE FUNCTION LOG_PURCHASE_ITEM_CHANGE();
+
+CREATE FUNCTION LOG_PURCHASE_USER_CHANGE() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO CHANGE_LOG(ENTITY, ENTITY_ID, PURCHASE_ID, USER_ID, OPERATION)
+            VALUES ('PURCHASE_USER', OLD.PURCHASE_ID, OLD.PURCHASE_ID, OLD.USER_ID, 'DELETE');
+        RETURN OLD;
+    END IF;
+    INSERT INTO CHANGE_LOG(ENTITY, ENTITY_ID, PURCHASE_ID, USER_ID, OPERATION)
+        VALUES ('PURCHASE_USER', NEW.PURCHASE_ID, NEW.PURCHASE_ID, NEW.USER_ID, 'UPSERT');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER PURCHASE_USER_CHANGE_LOG AFTER INSERT OR UPDATE OR DELETE ON PURCHASE_USER
+    FOR EACH ROW EXECUTE FUNCTION LOG_PURCHASE_USER_CHANGE();
+
+-- Results of applied client mutations, so a retried batch is not applied twice.
+CREATE TABLE SYNC_MUTATION
+(
+    USER_ID    BIGINT REFERENCES MARKET_USER (ID) ON DELETE CASCADE NOT NULL,
+    CLIENT_ID  VARCHAR(100)                                         NOT NULL,
+    RESULT     TEXT                                                 NOT NULL,
+    CREATED_AT TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (USER_ID, CLIENT_ID)
+);
@@ -0,0 +1,38 @@
+\c market_list;
+
+ALTER TABLE PURCHASE_ITEM ADD COLUMN VERSION BIGINT DEFAULT 1 NOT NULL;
+ALTER TABLE PRODUCT ADD COLUMN VERSION BIGINT DEFAULT 1 NOT NULL;
+
+-- The version only moves when the row content actually changes, so ETags stay stable on no-op updates.
+CREATE OR REPLACE FUNCTION TOUCH_PURCHASE_ITEM() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF NEW IS DISTINCT FROM OLD THEN
+        NEW.VERSION = OLD.VERSION + 1;
+    END IF;
+    NEW.UPDATED_AT = NOW();
+    IF NEW.PURCHASED IS DISTINCT FROM OLD.PURCHASED AND NEW.PURCHASED_UPDATED_AT IS NOT DISTINCT FROM OLD.PURCHASED_UPDATED_AT THEN
+        NEW.PURCHASED_UPDATED_AT = NOW();
+    END IF;
+    IF NEW.QUANTITY IS DISTINCT FROM OLD.QUANTITY AND NEW.QUANTITY_UPDATED_AT IS NOT DISTINCT FROM OLD.QUANTITY_UPDATED_AT THEN
+        NEW.QUANTITY_UPDATED_AT = NOW();
+    END IF;
+    IF NEW.PRICE IS DISTINCT FROM OLD.PRICE AND NEW.PRICE_UPDATED_AT IS NOT DISTINCT FROM OLD.PRICE_UPDATED_AT THEN
+        NEW.PRICE_UPDATED_AT = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION TOUCH_PRODUCT() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF (NEW.EAN, NEW.NAME, NEW.UNIT, NEW.SIZE) IS DISTINCT FROM (OLD.EAN, OLD.NAME, OLD.UNIT, OLD.SIZE) THEN
+        NEW.VERSION = OLD.VERSION + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER PRODUCT_TOUCH BEFORE UPDATE ON PRODUCT
+    FOR EACH ROW EXECUTE FUNCTION TOUCH_PRODUCT();
@@ -0,0 +1,6 @@
+\c market_list;
+
+ALTER TABLE PURCHASE_ITEM
+    ADD COLUMN NOTES    VARCHAR(500),
+    ADD COLUMN PRIORITY SMALLINT DEFAULT 1     NOT NULL,
+    ADD COLUMN OPTIONAL BOOLEAN  DEFAULT FALSE NOT NULL;
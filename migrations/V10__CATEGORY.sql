@@ -0,0 +1,39 @@
+\c market_list;
+
+CREATE TABLE CATEGORY
+(
+    ID         BIGSERIAL PRIMARY KEY,
+    NAME       VARCHAR(100) NOT NULL,
+    CREATED_AT TIMESTAMP DEFAULT NOW(),
+    UPDATED_AT TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX CATEGORY_NAME_UNIQUE ON CATEGORY (LOWER(NAME));
+
+INSERT INTO CATEGORY(NAME)
+VALUES ('Produce'),
+       ('Dairy'),
+       ('Bakery'),
+       ('Meat & Fish'),
+       ('Frozen'),
+       ('Pantry'),
+       ('Snacks'),
+       ('Beverages'),
+       ('Cleaning'),
+       ('Personal Care'),
+       ('Household'),
+       ('Pets');
+
+ALTER TABLE PRODUCT ADD COLUMN CATEGORY_ID BIGINT REFERENCES CATEGORY (ID) ON DELETE SET NULL;
+
+CREATE INDEX PRODUCT_NAME_TRGM ON PRODUCT USING GIN (NAME GIN_TRGM_OPS);
+
+CREATE OR REPLACE FUNCTION TOUCH_PRODUCT() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF (NEW.EAN, NEW.NAME, NEW.UNIT, NEW.SIZE, NEW.CATEGORY_ID) IS DISTINCT FROM (OLD.EAN, OLD.NAME, OLD.UNIT, OLD.SIZE, OLD.CATEGORY_ID) THEN
+        NEW.VERSION = OLD.VERSION + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
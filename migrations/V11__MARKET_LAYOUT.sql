@@ -0,0 +1,33 @@
+\c market_list;
+
+CREATE TABLE MARKET_AISLE
+(
+    ID         BIGSERIAL PRIMARY KEY,
+    MARKET_ID  BIGINT REFERENCES MARKET (ID) ON DELETE CASCADE NOT NULL,
+    NAME       VARCHAR(100)                                   NOT NULL,
+    POSITION   INT                                            NOT NULL,
+    CREATED_AT TIMESTAMP DEFAULT NOW(),
+    UNIQUE (MARKET_ID, POSITION)
+);
+
+-- A category or product sits in at most one aisle of each market, products taking precedence over their category.
+CREATE TABLE MARKET_AISLE_CATEGORY
+(
+    MARKET_ID   BIGINT REFERENCES MARKET (ID) ON DELETE CASCADE       NOT NULL,
+    CATEGORY_ID BIGINT REFERENCES CATEGORY (ID) ON DELETE CASCADE     NOT NULL,
+    AISLE_ID    BIGINT REFERENCES MARKET_AISLE (ID) ON DELETE CASCADE NOT NULL,
+    PRIMARY KEY (MARKET_ID, CATEGORY_ID)
+);
+
+CREATE TABLE MARKET_AISLE_PRODUCT
+(
+    MARKET_ID  BIGINT REFERENCES MARKET (ID) ON DELETE CASCADE       NOT NULL,
+    PRODUCT_ID BIGINT REFERENCES PRODUCT (ID) ON DELETE CASCADE      NOT NULL,
+    AISLE_ID   BIGINT REFERENCES MARKET_AISLE (ID) ON DELETE CASCADE NOT NULL,
+    PRIMARY KEY (MARKET_ID, PRODUCT_ID)
+);
+
+CREATE INDEX PURCHASE_MARKET_ID ON PURCHASE (MARKET_ID);
+
+-- Check-off order of past purchases, used to learn the walking order of each market.
+CREATE INDEX PURCHASE_ITEM_CHECK_ORDER ON PURCHASE_ITEM (PURCHASE_ID, PURCHASED_UPDATED_AT) WHERE PURCHASED;
@@ -0,0 +1,27 @@
+\c market_list;
+
+-- Positions are spaced by 65536 so an item can be moved between two others without renumbering the list.
+ALTER TABLE PURCHASE_ITEM ADD COLUMN POSITION BIGINT;
+
+UPDATE PURCHASE_ITEM pi
+SET POSITION = ordered.RN * 65536
+FROM (SELECT ID, ROW_NUMBER() OVER (PARTITION BY PURCHASE_ID ORDER BY ID) RN FROM PURCHASE_ITEM) ordered
+WHERE pi.ID = ordered.ID;
+
+ALTER TABLE PURCHASE_ITEM ALTER COLUMN POSITION SET NOT NULL;
+
+CREATE INDEX PURCHASE_ITEM_POSITION ON PURCHASE_ITEM (PURCHASE_ID, POSITION);
+
+-- New items go to the end of the list unless a position is given.
+CREATE FUNCTION POSITION_PURCHASE_ITEM() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF NEW.POSITION IS NULL THEN
+        NEW.POSITION = COALESCE((SELECT MAX(POSITION) FROM PURCHASE_ITEM WHERE PURCHASE_ID = NEW.PURCHASE_ID), 0) + 65536;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER PURCHASE_ITEM_POSITION BEFORE INSERT ON PURCHASE_ITEM
+    FOR EACH ROW EXECUTE FUNCTION POSITION_PURCHASE_ITEM();
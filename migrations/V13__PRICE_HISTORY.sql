@@ -0,0 +1,4 @@
+\c market_list;
+
+-- Prices paid for a product, read by the price history of the product.
+CREATE INDEX PURCHASE_ITEM_PRODUCT_PRICE ON PURCHASE_ITEM (PRODUCT_ID) WHERE PURCHASED AND PRICE IS NOT NULL;
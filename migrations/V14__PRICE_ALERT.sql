@@ -0,0 +1,36 @@
+\c market_list;
+
+CREATE TABLE PRODUCT_WATCH
+(
+    ID            BIGSERIAL PRIMARY KEY,
+    USER_ID       BIGINT REFERENCES MARKET_USER (ID) ON DELETE CASCADE NOT NULL,
+    PRODUCT_ID    BIGINT REFERENCES PRODUCT (ID) ON DELETE CASCADE     NOT NULL,
+    PRICE_BELOW   BIGINT,
+    SPIKE_PERCENT INT,
+    CREATED_AT    TIMESTAMP DEFAULT NOW(),
+    UPDATED_AT    TIMESTAMP DEFAULT NOW(),
+    UNIQUE (USER_ID, PRODUCT_ID),
+    CHECK (PRICE_BELOW IS NOT NULL OR SPIKE_PERCENT IS NOT NULL)
+);
+
+CREATE INDEX PRODUCT_WATCH_PRODUCT ON PRODUCT_WATCH (PRODUCT_ID);
+
+CREATE TABLE PRICE_ALERT
+(
+    ID               BIGSERIAL PRIMARY KEY,
+    USER_ID          BIGINT REFERENCES MARKET_USER (ID) ON DELETE CASCADE    NOT NULL,
+    WATCH_ID         BIGINT REFERENCES PRODUCT_WATCH (ID) ON DELETE CASCADE NOT NULL,
+    PRODUCT_ID       BIGINT REFERENCES PRODUCT (ID) ON DELETE CASCADE       NOT NULL,
+    MARKET_ID        BIGINT REFERENCES MARKET (ID),
+    PURCHASE_ID      BIGINT REFERENCES PURCHASE (ID) ON DELETE SET NULL,
+    PURCHASE_ITEM_ID BIGINT REFERENCES PURCHASE_ITEM (ID) ON DELETE SET NULL,
+    TYPE             VARCHAR(20)                                            NOT NULL,
+    PRICE            BIGINT                                                 NOT NULL,
+    REFERENCE_PRICE  BIGINT                                                 NOT NULL,
+    CREATED_AT       TIMESTAMP DEFAULT NOW(),
+    READ_AT          TIMESTAMP
+);
+
+-- Setting an item to a price it already alerted about does not alert again.
+CREATE UNIQUE INDEX PRICE_ALERT_UNIQUE ON PRICE_ALERT (WATCH_ID, PURCHASE_ITEM_ID, TYPE, PRICE);
+CREATE INDEX PRICE_ALERT_INBOX ON PRICE_ALERT (USER_ID, CREATED_AT DESC);
@@ -0,0 +1,4 @@
+\c market_list;
+
+-- Timezone the reports of the user are grouped in, the connection itself always works in UTC.
+ALTER TABLE MARKET_USER ADD COLUMN TIMEZONE VARCHAR(64) DEFAULT 'UTC' NOT NULL;
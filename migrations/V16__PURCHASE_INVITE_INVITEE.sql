@@ -0,0 +1,13 @@
+\c market_list;
+
+-- Invites are matched by the invited user instead of the email, which the user can change. Invites to addresses
+-- without an account keep no invitee until someone signs up with the address.
+ALTER TABLE PURCHASE_INVITE
+    ADD COLUMN INVITEE_ID BIGINT REFERENCES MARKET_USER (ID) ON DELETE CASCADE;
+
+UPDATE PURCHASE_INVITE i SET INVITEE_ID = u.ID
+    FROM MARKET_USER u
+    WHERE LOWER(u.EMAIL) = LOWER(i.EMAIL);
+
+CREATE INDEX PURCHASE_INVITE_INVITEE ON PURCHASE_INVITE (INVITEE_ID) WHERE STATUS = 'PENDING';
+CREATE INDEX PURCHASE_INVITE_UNBOUND ON PURCHASE_INVITE (LOWER(EMAIL)) WHERE STATUS = 'PENDING' AND INVITEE_ID IS NULL;
@@ -0,0 +1,4 @@
+\c market_list;
+
+-- Administrators maintain the data shared by every user, like the category taxonomy and the market layouts.
+ALTER TABLE MARKET_USER ADD COLUMN IS_ADMIN BOOLEAN DEFAULT FALSE NOT NULL;
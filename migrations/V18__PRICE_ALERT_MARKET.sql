@@ -0,0 +1,6 @@
+\c market_list;
+
+-- Alerts outlive the market they were raised at, like they outlive their purchase.
+ALTER TABLE PRICE_ALERT
+    DROP CONSTRAINT PRICE_ALERT_MARKET_ID_FKEY,
+    ADD CONSTRAINT PRICE_ALERT_MARKET_ID_FKEY FOREIGN KEY (MARKET_ID) REFERENCES MARKET (ID) ON DELETE SET NULL;
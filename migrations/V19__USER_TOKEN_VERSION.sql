@@ -0,0 +1,4 @@
+\c market_list;
+
+-- Stamped into the issued tokens and bumped when the password changes, which revokes the tokens issued before.
+ALTER TABLE MARKET_USER ADD COLUMN TOKEN_VERSION BIGINT DEFAULT 0 NOT NULL;
@@ -0,0 +1,15 @@
+\c market_list;
+
+-- CHANGE_LOG and SYNC_MUTATION rows are pruned once they are older than the sync retention. PRUNED_TX_ID is the
+-- oldest transaction whose changes are all still logged: clients with an older sync token get a full sync.
+CREATE TABLE SYNC_RETENTION
+(
+    ID           BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (ID),
+    PRUNED_TX_ID BIGINT DEFAULT 0 NOT NULL,
+    PRUNED_AT    TIMESTAMP
+);
+
+INSERT INTO SYNC_RETENTION DEFAULT VALUES;
+
+CREATE INDEX CHANGE_LOG_CHANGED_AT ON CHANGE_LOG (CHANGED_AT);
+CREATE INDEX SYNC_MUTATION_CREATED_AT ON SYNC_MUTATION (CREATED_AT);
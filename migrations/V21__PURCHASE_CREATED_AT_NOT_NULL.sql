@@ -0,0 +1,9 @@
+\c market_list;
+
+-- The purchase list pages by CREATED_AT, a NULL value would produce a cursor that cannot be resumed.
+UPDATE PURCHASE p
+SET CREATED_AT = COALESCE((SELECT MIN(pi.CREATED_AT) FROM PURCHASE_ITEM pi WHERE pi.PURCHASE_ID = p.ID), NOW())
+WHERE p.CREATED_AT IS NULL;
+
+ALTER TABLE PURCHASE ALTER COLUMN CREATED_AT SET DEFAULT NOW();
+ALTER TABLE PURCHASE ALTER COLUMN CREATED_AT SET NOT NULL;
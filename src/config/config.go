@@ -8,6 +8,7 @@ import (
 	"github.com/knadh/koanf/v2"
 	"log"
 	"strings"
+	"time"
 )
 
 var k *koanf.Koanf
@@ -51,3 +52,51 @@ func GetTlsCrtPath() string {
 func GetTlsKeyPath() string {
 	return k.String("server.tls.key")
 }
+
+func GetAuthSecret() string {
+	return k.String("auth.secret")
+}
+
+func GetAuthTokenExpiration() time.Duration {
+	return k.Duration("auth.expiration")
+}
+
+func GetPasswordCost() int {
+	return k.Int("auth.password.cost")
+}
+
+func GetPasswordResetExpiration() time.Duration {
+	return k.Duration("auth.reset.expiration")
+}
+
+func GetSyncRetention() time.Duration {
+	return k.Duration("sync.retention")
+}
+
+func GetPasswordResetSender() string {
+	return k.String("auth.reset.sender")
+}
+
+func GetPasswordResetUrl() string {
+	return k.String("auth.reset.url")
+}
+
+func GetSmtpHost() string {
+	return k.String("smtp.host")
+}
+
+func GetSmtpPort() string {
+	return k.String("smtp.port")
+}
+
+func GetSmtpUsername() string {
+	return k.String("smtp.username")
+}
+
+func GetSmtpPassword() string {
+	return k.String("smtp.password")
+}
+
+func GetSmtpFrom() string {
+	return k.String("smtp.from")
+}
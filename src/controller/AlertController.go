@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/service"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+	"strconv"
+)
+
+type AlertController struct {
+	AlertService service.AlertService
+}
+
+func CreateAlertController(alertService service.AlertService) *AlertController {
+	return &AlertController{
+		AlertService: alertService,
+	}
+}
+
+func (a AlertController) Register(echo *echo.Echo) error {
+	watch := echo.Group("/v1/watch")
+	watch.POST("/", a.SaveWatch)
+	watch.PUT("/:id", a.UpdateWatch)
+	watch.DELETE("/:id", a.DeleteWatch)
+	watch.GET("/", a.GetAllWatches)
+
+	alert := echo.Group("/v1/alert")
+	alert.GET("/", a.GetAlerts)
+	alert.POST("/:id/read", a.MarkAlertRead)
+
+	return nil
+}
+
+func (a AlertController) SaveWatch(c echo.Context) error {
+	var watch model.ProductWatch
+
+	if err := c.Bind(&watch); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	watch, err := a.AlertService.SaveWatch(c.Request().Context(), watch)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, watch)
+}
+
+func (a AlertController) UpdateWatch(c echo.Context) error {
+	var watch model.ProductWatch
+
+	if err := c.Bind(&watch); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Watch Id"))
+	}
+	watch.Id = &idValue
+
+	watch, err = a.AlertService.UpdateWatch(c.Request().Context(), watch)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, watch)
+}
+
+func (a AlertController) DeleteWatch(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Watch Id"))
+	}
+
+	err = a.AlertService.DeleteWatch(c.Request().Context(), idValue)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (a AlertController) GetAllWatches(c echo.Context) error {
+	watches, err := a.AlertService.ListWatches(c.Request().Context())
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, watches)
+}
+
+func (a AlertController) GetAlerts(c echo.Context) error {
+	unreadOnly := false
+	if value := c.QueryParam("unread"); len(value) > 0 {
+		var err error
+		unreadOnly, err = strconv.ParseBool(value)
+		if err != nil {
+			return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid unread"))
+		}
+	}
+
+	alerts, err := a.AlertService.ListAlerts(c.Request().Context(), unreadOnly)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, alerts)
+}
+
+func (a AlertController) MarkAlertRead(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Alert Id"))
+	}
+
+	alert, err := a.AlertService.MarkAlertRead(c.Request().Context(), idValue)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, alert)
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/service"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+)
+
+type AuthController struct {
+	AuthService service.AuthService
+}
+
+func CreateAuthController(authService service.AuthService) *AuthController {
+	return &AuthController{
+		AuthService: authService,
+	}
+}
+
+func (a AuthController) Register(echo *echo.Echo) error {
+	v1 := echo.Group("/v1/auth")
+	v1.POST("/signup", a.SignUp)
+	v1.POST("/login", a.Login)
+
+	return nil
+}
+
+func (a AuthController) PublicRoutes() []string {
+	return []string{
+		http.MethodPost + " /v1/auth/signup",
+		http.MethodPost + " /v1/auth/login",
+	}
+}
+
+func (a AuthController) SignUp(c echo.Context) error {
+	var user model.User
+
+	if err := c.Bind(&user); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	token, err := a.AuthService.SignUp(c.Request().Context(), user)
+
+	if err != nil {
+		var mkError *util.MarketListError
+		if errors.As(err, &mkError) {
+			switch mkError.ErrorType {
+			case util.INVALID_INPUT:
+				return handleError(c, http.StatusBadRequest, mkError)
+			case util.ALREADY_EXISTS:
+				return handleError(c, http.StatusConflict, mkError)
+			}
+		}
+		return handleError(c, http.StatusUnprocessableEntity, err)
+	}
+
+	return c.JSON(http.StatusCreated, token)
+}
+
+func (a AuthController) Login(c echo.Context) error {
+	var credentials model.Credentials
+
+	if err := c.Bind(&credentials); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	token, err := a.AuthService.Login(c.Request().Context(), credentials)
+
+	if err != nil {
+		var mkError *util.MarketListError
+		if errors.As(err, &mkError) && mkError.ErrorType == util.UNAUTHORIZED {
+			return handleError(c, http.StatusUnauthorized, mkError)
+		}
+		return handleError(c, http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, token)
+}
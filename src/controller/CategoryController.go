@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/service"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+	"strconv"
+)
+
+type CategoryController struct {
+	CategoryService service.CategoryService
+}
+
+func CreateCategoryController(categoryService service.CategoryService) *CategoryController {
+	return &CategoryController{
+		CategoryService: categoryService,
+	}
+}
+
+func (cc CategoryController) Register(echo *echo.Echo) error {
+	v1 := echo.Group("/v1/category")
+	v1.POST("/", cc.CreateCategory)
+	v1.PUT("/:id", cc.UpdateCategory)
+	v1.DELETE("/:id", cc.DeleteCategory)
+	v1.GET("/suggest", cc.SuggestCategory)
+	v1.GET("/:id", cc.GetCategory)
+	v1.GET("/", cc.GetAllCategories)
+
+	return nil
+}
+
+func (cc CategoryController) CreateCategory(c echo.Context) error {
+	var category model.Category
+
+	if err := c.Bind(&category); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	category, err := cc.CategoryService.Create(c.Request().Context(), category)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, category)
+}
+
+func (cc CategoryController) UpdateCategory(c echo.Context) error {
+	var category model.Category
+
+	if err := c.Bind(&category); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Category Id"))
+	}
+	category.Id = &idValue
+
+	category, err = cc.CategoryService.Update(c.Request().Context(), category)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, category)
+}
+
+func (cc CategoryController) DeleteCategory(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Category Id"))
+	}
+
+	err = cc.CategoryService.Delete(c.Request().Context(), idValue)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (cc CategoryController) GetCategory(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Category Id"))
+	}
+
+	category, err := cc.CategoryService.GetById(c.Request().Context(), idValue)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, category)
+}
+
+func (cc CategoryController) GetAllCategories(c echo.Context) error {
+	categories, err := cc.CategoryService.List(c.Request().Context())
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, categories)
+}
+
+func (cc CategoryController) SuggestCategory(c echo.Context) error {
+	category, err := cc.CategoryService.Suggest(c.Request().Context(), c.QueryParam("name"))
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, category)
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ITEM_ETAG_HEADER carries the ETag of the item changed by a request that responds with the whole purchase.
+const ITEM_ETAG_HEADER = "Item-ETag"
+
+func productETag(product model.Product) string {
+	return fmt.Sprintf(`"%d"`, product.Version)
+}
+
+// itemETag also carries the version of the embedded product, so a cached item is refreshed when its product changes.
+// Only the item version is checked by If-Match.
+func itemETag(item model.PurchaseItem) string {
+	return fmt.Sprintf(`"%d-%d"`, item.Version, item.Product.Version)
+}
+
+// contentETag is a weak ETag for representations without a version of their own, derived from the response body.
+func contentETag(body interface{}) (string, error) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`, nil
+}
+
+// parseIfMatch returns the version the client expects to modify, or nil when the request is unconditional.
+// A value that is not one of our version ETags can never match and fails with PRECONDITION_FAILED.
+func parseIfMatch(c echo.Context) (*int64, error) {
+	header := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	if len(header) == 0 || header == "*" {
+		return nil, nil
+	}
+
+	value := strings.Trim(header, `"`)
+	value, _, _ = strings.Cut(value, "-")
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || strings.HasPrefix(header, "W/") {
+		return nil, util.MakeError(util.PRECONDITION_FAILED, fmt.Sprintf("If-Match %s does not match the current version", header))
+	}
+
+	return &version, nil
+}
+
+// isNotModified reports whether any ETag of If-None-Match matches etag, using the weak comparison.
+func isNotModified(c echo.Context, etag string) bool {
+	header := c.Request().Header.Get("If-None-Match")
+	if len(header) == 0 {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonWithETag writes body with its ETag, answering 304 Not Modified when the client already holds it.
+func jsonWithETag(c echo.Context, status int, etag string, body interface{}) error {
+	c.Response().Header().Set("ETag", etag)
+	if c.Request().Method == http.MethodGet && isNotModified(c, etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(status, body)
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func contextWithHeader(name, value string) echo.Context {
+	request := httptest.NewRequest(http.MethodPut, "/", nil)
+	if len(value) > 0 {
+		request.Header.Set(name, value)
+	}
+	return echo.New().NewContext(request, httptest.NewRecorder())
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version *int64
+		failed  bool
+	}{
+		{name: "no header"},
+		{name: "any version", header: "*"},
+		{name: "product version", header: `"3"`, version: int64Pointer(3)},
+		{name: "item version with its product version", header: `"3-7"`, version: int64Pointer(3)},
+		{name: "unquoted version", header: "3", version: int64Pointer(3)},
+		{name: "surrounding spaces", header: ` "3" `, version: int64Pointer(3)},
+		{name: "weak content ETag", header: `W/"0123456789abcdef01234567"`, failed: true},
+		{name: "weak version", header: `W/"3"`, failed: true},
+		{name: "not a version", header: `"abc"`, failed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			version, err := parseIfMatch(contextWithHeader("If-Match", test.header))
+			if test.failed {
+				var mkError *util.MarketListError
+				if !errors.As(err, &mkError) || mkError.ErrorType != util.PRECONDITION_FAILED {
+					t.Fatalf("err = %v, want PRECONDITION_FAILED", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if (version == nil) != (test.version == nil) || (version != nil && *version != *test.version) {
+				t.Errorf("version = %v, want %v", version, test.version)
+			}
+		})
+	}
+}
+
+func TestIsNotModified(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		etag        string
+		notModified bool
+	}{
+		{name: "no header", etag: `"3-7"`},
+		{name: "same ETag", header: `"3-7"`, etag: `"3-7"`, notModified: true},
+		{name: "other ETag", header: `"3-6"`, etag: `"3-7"`},
+		{name: "one of a list", header: `"2-7", "3-7"`, etag: `"3-7"`, notModified: true},
+		{name: "any ETag", header: "*", etag: `"3-7"`, notModified: true},
+		{name: "weak header against a strong ETag", header: `W/"3-7"`, etag: `"3-7"`, notModified: true},
+		{name: "strong header against a weak ETag", header: `"abc"`, etag: `W/"abc"`, notModified: true},
+		{name: "weak ETags", header: `W/"abc"`, etag: `W/"abc"`, notModified: true},
+		{name: "other weak ETag", header: `W/"abd"`, etag: `W/"abc"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notModified := isNotModified(contextWithHeader("If-None-Match", test.header), test.etag)
+			if notModified != test.notModified {
+				t.Errorf("isNotModified = %v, want %v", notModified, test.notModified)
+			}
+		})
+	}
+}
+
+func int64Pointer(value int64) *int64 {
+	return &value
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	controllerModel "github.com/ronistone/market-list/src/controller/model"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/service"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+	"strconv"
+)
+
+type InviteController struct {
+	InviteService service.InviteService
+}
+
+func CreateInviteController(inviteService service.InviteService) *InviteController {
+	return &InviteController{
+		InviteService: inviteService,
+	}
+}
+
+func (i InviteController) Register(echo *echo.Echo) error {
+	v1 := echo.Group("/v1/invite")
+	v1.GET("/", i.GetPendingInvites)
+	v1.POST("/:id/accept", i.AcceptInvite)
+	v1.POST("/:id/decline", i.DeclineInvite)
+
+	purchase := echo.Group("/v1/purchase")
+	purchase.POST("/:id/invite", i.CreateInvite)
+
+	return nil
+}
+
+func (i InviteController) CreateInvite(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
+	}
+
+	var invite model.Invite
+	if err := c.Bind(&invite); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	invite, err = i.InviteService.Create(c.Request().Context(), idValue, invite.Email, invite.Role)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	inviteFiltered := controllerModel.Invite{}
+	inviteFiltered.FromModel(invite)
+
+	return c.JSON(http.StatusCreated, inviteFiltered)
+}
+
+func (i InviteController) GetPendingInvites(c echo.Context) error {
+	invites, err := i.InviteService.ListPending(c.Request().Context())
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	invitesFiltered := make([]controllerModel.Invite, len(invites))
+	for j := range invites {
+		invitesFiltered[j].FromModel(invites[j])
+	}
+
+	return c.JSON(http.StatusOK, invitesFiltered)
+}
+
+func (i InviteController) AcceptInvite(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Invite Id"))
+	}
+
+	purchase, err := i.InviteService.Accept(c.Request().Context(), idValue)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	purchaseFiltered := controllerModel.Purchase{}
+	purchaseFiltered.FromModel(purchase)
+
+	return c.JSON(http.StatusOK, purchaseFiltered)
+}
+
+func (i InviteController) DeclineInvite(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Invite Id"))
+	}
+
+	err = i.InviteService.Decline(c.Request().Context(), idValue)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/service"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+	"strconv"
+)
+
+type MarketLayoutController struct {
+	MarketLayoutService service.MarketLayoutService
+}
+
+func CreateMarketLayoutController(marketLayoutService service.MarketLayoutService) *MarketLayoutController {
+	return &MarketLayoutController{
+		MarketLayoutService: marketLayoutService,
+	}
+}
+
+func (m MarketLayoutController) Register(echo *echo.Echo) error {
+	v1 := echo.Group("/v1/market")
+	v1.GET("/:id/layout", m.GetLayout)
+	v1.PUT("/:id/layout", m.SaveLayout)
+
+	return nil
+}
+
+func (m MarketLayoutController) GetLayout(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Market Id"))
+	}
+
+	layout, err := m.MarketLayoutService.GetLayout(c.Request().Context(), idValue)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, layout)
+}
+
+func (m MarketLayoutController) SaveLayout(c echo.Context) error {
+	var layout model.MarketLayout
+
+	if err := c.Bind(&layout); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Market Id"))
+	}
+	layout.MarketId = idValue
+
+	layout, err = m.MarketLayoutService.SaveLayout(c.Request().Context(), layout)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, layout)
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ronistone/market-list/src/service"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+	"strconv"
+)
+
+type MarketRecommendationController struct {
+	MarketRecommendationService service.MarketRecommendationService
+}
+
+func CreateMarketRecommendationController(marketRecommendationService service.MarketRecommendationService) *MarketRecommendationController {
+	return &MarketRecommendationController{
+		MarketRecommendationService: marketRecommendationService,
+	}
+}
+
+func (m MarketRecommendationController) Register(echo *echo.Echo) error {
+	purchase := echo.Group("/v1/purchase")
+	purchase.GET("/:id/recommendation", m.Recommend)
+
+	return nil
+}
+
+func (m MarketRecommendationController) Recommend(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
+	}
+
+	split := false
+	if value := c.QueryParam("split"); len(value) > 0 {
+		split, err = strconv.ParseBool(value)
+		if err != nil {
+			return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid split"))
+		}
+	}
+
+	recommendation, err := m.MarketRecommendationService.Recommend(c.Request().Context(), idValue, split)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, recommendation)
+}
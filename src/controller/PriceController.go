@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/service"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+	"strconv"
+)
+
+type PriceController struct {
+	PriceService service.PriceService
+}
+
+func CreatePriceController(priceService service.PriceService) *PriceController {
+	return &PriceController{
+		PriceService: priceService,
+	}
+}
+
+func (p PriceController) Register(echo *echo.Echo) error {
+	product := echo.Group("/v1/product")
+	product.GET("/:id/prices", p.GetProductPrices)
+	product.GET("/ean/:ean/prices", p.GetProductPricesByEan)
+
+	return nil
+}
+
+func parsePriceFilter(c echo.Context) (model.PriceFilter, error) {
+	var filter model.PriceFilter
+	var err error
+
+	if filter.From, err = parseQueryDate(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseQueryDate(c, "to", true); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func (p PriceController) GetProductPrices(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Product Id"))
+	}
+
+	filter, err := parsePriceFilter(c)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	history, err := p.PriceService.GetProductPrices(c.Request().Context(), idValue, filter)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
+
+func (p PriceController) GetProductPricesByEan(c echo.Context) error {
+	filter, err := parsePriceFilter(c)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	history, err := p.PriceService.GetProductPricesByEan(c.Request().Context(), c.Param("ean"), filter)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
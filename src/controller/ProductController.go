@@ -36,6 +36,25 @@ func handleError(echo echo.Context, statusCode int, err error) error {
 	return echo.JSON(statusCode, err)
 }
 
+func handleServiceError(c echo.Context, err error) error {
+	var mkError *util.MarketListError
+	if errors.As(err, &mkError) {
+		switch mkError.ErrorType {
+		case util.INVALID_INPUT:
+			return handleError(c, http.StatusBadRequest, mkError)
+		case util.ALREADY_EXISTS:
+			return handleError(c, http.StatusConflict, mkError)
+		case util.NOT_FOUND:
+			return handleError(c, http.StatusNotFound, mkError)
+		case util.FORBIDDEN:
+			return handleError(c, http.StatusForbidden, mkError)
+		case util.PRECONDITION_FAILED:
+			return handleError(c, http.StatusPreconditionFailed, mkError)
+		}
+	}
+	return handleError(c, http.StatusInternalServerError, err)
+}
+
 func (p ProductController) UpdateProduct(c echo.Context) error {
 	var product model.Product
 
@@ -50,12 +69,22 @@ func (p ProductController) UpdateProduct(c echo.Context) error {
 	}
 	product.Id = &idValue
 
-	product, err = p.productService.Update(c.Request().Context(), product)
+	expectedVersion, err := parseIfMatch(c)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	product, err = p.productService.Update(c.Request().Context(), product, expectedVersion)
 
 	if err != nil {
+		var mkError *util.MarketListError
+		if errors.As(err, &mkError) && (mkError.ErrorType == util.PRECONDITION_FAILED || mkError.ErrorType == util.NOT_FOUND) {
+			return handleServiceError(c, mkError)
+		}
 		return handleError(c, http.StatusUnprocessableEntity, err)
 	}
 
+	c.Response().Header().Set("ETag", productETag(product))
 	return c.JSON(http.StatusCreated, product)
 }
 
@@ -104,7 +133,7 @@ func (p ProductController) GetProductById(c echo.Context) error {
 		return handleError(c, http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusOK, products)
+	return jsonWithETag(c, http.StatusOK, productETag(products), products)
 }
 
 func (p ProductController) GetProductByEan(c echo.Context) error {
@@ -116,5 +145,5 @@ func (p ProductController) GetProductByEan(c echo.Context) error {
 		return handleError(c, http.StatusNotFound, err)
 	}
 
-	return c.JSON(http.StatusOK, products)
+	return jsonWithETag(c, http.StatusOK, productETag(products), products)
 }
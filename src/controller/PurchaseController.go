@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
 	controllerModel "github.com/ronistone/market-list/src/controller/model"
 	"github.com/ronistone/market-list/src/model"
@@ -9,8 +12,11 @@ import (
 	"github.com/ronistone/market-list/src/util"
 	"net/http"
 	"strconv"
+	"time"
 )
 
+const PURCHASE_EVENT_KEEP_ALIVE = 25 * time.Second
+
 type PurchaseController struct {
 	PurchaseService service.PurchaseService
 }
@@ -24,13 +30,23 @@ func CreatePurchaseController(purchaseService service.PurchaseService) *Purchase
 func (p PurchaseController) Register(echo *echo.Echo) error {
 	v1 := echo.Group("/v1/purchase")
 	v1.POST("/", p.CreatePurchase)
+	v1.PUT("/:id", p.ReplacePurchase)
+	v1.PATCH("/:id", p.UpdatePurchase)
 	v1.DELETE("/:id", p.DeletePurchase)
+	v1.POST("/:id/clone", p.ClonePurchase)
+	v1.POST("/:id/leave", p.LeavePurchase)
+	v1.PUT("/:id/user/:userId", p.UpdateParticipantRole)
+	v1.DELETE("/:id/user/:userId", p.RemoveParticipant)
 	v1.POST("/:id/item/", p.AddItem)
+	v1.POST("/:id/consolidate", p.ConsolidateItems)
+	v1.POST("/:id/items\\:batch", p.ApplyItemBatch)
 	v1.DELETE("/:id/item/:itemId", p.RemoveItem)
 	v1.GET("/:id", p.GetPurchase)
 	v1.GET("/", p.GetAllPurchase)
 	v1.PUT("/:id/item/:itemId", p.UpdateItem)
+	v1.POST("/:id/item/:itemId/move", p.MoveItem)
 	v1.GET("/:id/item/:itemId", p.GetItem)
+	v1.GET("/:id/events", p.StreamEvents)
 
 	return nil
 }
@@ -45,7 +61,7 @@ func (p PurchaseController) CreatePurchase(c echo.Context) error {
 	purchase, err := p.PurchaseService.CreatePurchase(c.Request().Context(), purchase)
 
 	if err != nil {
-		return handleError(c, http.StatusUnprocessableEntity, err)
+		return handleServiceError(c, err)
 	}
 
 	purchaseFiltered := controllerModel.Purchase{}
@@ -61,19 +77,41 @@ func (p PurchaseController) AddItem(c echo.Context) error {
 		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
 	}
 
+	merge := true
+	if value := c.QueryParam("merge"); len(value) > 0 {
+		merge, err = strconv.ParseBool(value)
+		if err != nil {
+			return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid merge"))
+		}
+	}
+
 	var purchaseItem model.PurchaseItem
 	if err := c.Bind(&purchaseItem); err != nil {
 		return handleError(c, http.StatusBadRequest, err)
 	}
 
-	purchase, err := p.PurchaseService.AddItem(c.Request().Context(), idValue, purchaseItem)
+	purchase, err := p.PurchaseService.AddItem(c.Request().Context(), idValue, purchaseItem, merge)
 
 	if err != nil {
-		var mkError *util.MarketListError
-		if errors.As(err, &mkError) && mkError.ErrorType == util.NOT_FOUND {
-			return handleError(c, http.StatusNotFound, mkError)
-		}
-		return handleError(c, http.StatusInternalServerError, err)
+		return handleServiceError(c, err)
+	}
+
+	purchaseFiltered := controllerModel.Purchase{}
+	purchaseFiltered.FromModel(purchase)
+
+	return c.JSON(http.StatusOK, purchaseFiltered)
+}
+
+func (p PurchaseController) ConsolidateItems(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
+	}
+
+	purchase, err := p.PurchaseService.ConsolidateItems(c.Request().Context(), idValue)
+	if err != nil {
+		return handleServiceError(c, err)
 	}
 
 	purchaseFiltered := controllerModel.Purchase{}
@@ -98,11 +136,7 @@ func (p PurchaseController) RemoveItem(c echo.Context) error {
 	purchase, err := p.PurchaseService.RemoveItem(c.Request().Context(), idValue, itemIdValue)
 
 	if err != nil {
-		var mkError *util.MarketListError
-		if errors.As(err, &mkError) && mkError.ErrorType == util.NOT_FOUND {
-			return handleError(c, http.StatusNotFound, mkError)
-		}
-		return handleError(c, http.StatusInternalServerError, err)
+		return handleServiceError(c, err)
 	}
 
 	purchaseFiltered := controllerModel.Purchase{}
@@ -124,20 +158,64 @@ func (p PurchaseController) UpdateItem(c echo.Context) error {
 		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Item Id"))
 	}
 
+	expectedVersion, err := parseIfMatch(c)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
 	itemPurchase := model.PurchaseItem{}
 	err = (&echo.DefaultBinder{}).BindBody(c, &itemPurchase)
 	if err != nil {
 		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Item Update"))
 	}
 
-	purchase, err := p.PurchaseService.UpdateItem(c.Request().Context(), idValue, itemIdValue, itemPurchase)
+	purchase, err := p.PurchaseService.UpdateItem(c.Request().Context(), idValue, itemIdValue, itemPurchase, expectedVersion)
 
 	if err != nil {
-		var mkError *util.MarketListError
-		if errors.As(err, &mkError) && mkError.ErrorType == util.NOT_FOUND {
-			return handleError(c, http.StatusNotFound, mkError)
+		return handleServiceError(c, err)
+	}
+
+	// The body is the whole purchase, so it carries the ETag of the purchase. The ETag of the updated item, the one
+	// If-Match accepts for the next update, goes in ITEM_ETAG_HEADER.
+	for _, item := range purchase.Items {
+		if item.Id != nil && *item.Id == itemIdValue {
+			c.Response().Header().Set(ITEM_ETAG_HEADER, itemETag(item))
 		}
-		return handleError(c, http.StatusInternalServerError, err)
+	}
+
+	purchaseFiltered := controllerModel.Purchase{}
+	purchaseFiltered.FromModel(purchase)
+
+	etag, err := contentETag(purchaseFiltered)
+	if err != nil {
+		return handleError(c, http.StatusInternalServerError, util.MakeErrorUnknown(err))
+	}
+
+	return jsonWithETag(c, http.StatusOK, etag, purchaseFiltered)
+}
+
+func (p PurchaseController) MoveItem(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
+	}
+
+	itemId := c.Param("itemId")
+	itemIdValue, err := strconv.ParseInt(itemId, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Item Id"))
+	}
+
+	var move model.PurchaseItemMove
+	err = (&echo.DefaultBinder{}).BindBody(c, &move)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Item Move"))
+	}
+
+	purchase, err := p.PurchaseService.MoveItem(c.Request().Context(), idValue, itemIdValue, move)
+	if err != nil {
+		return handleServiceError(c, err)
 	}
 
 	purchaseFiltered := controllerModel.Purchase{}
@@ -146,6 +224,30 @@ func (p PurchaseController) UpdateItem(c echo.Context) error {
 	return c.JSON(http.StatusOK, purchaseFiltered)
 }
 
+func (p PurchaseController) ApplyItemBatch(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
+	}
+
+	var batch model.PurchaseItemBatch
+	err = (&echo.DefaultBinder{}).BindBody(c, &batch)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Item Batch"))
+	}
+
+	result, err := p.PurchaseService.ApplyItemBatch(c.Request().Context(), idValue, batch)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	resultFiltered := controllerModel.PurchaseItemBatchResult{}
+	resultFiltered.FromModel(result)
+
+	return c.JSON(http.StatusOK, resultFiltered)
+}
+
 func (p PurchaseController) GetPurchase(c echo.Context) error {
 	id := c.Param("id")
 	idValue, err := strconv.ParseInt(id, 10, 64)
@@ -153,36 +255,196 @@ func (p PurchaseController) GetPurchase(c echo.Context) error {
 		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
 	}
 
-	products, err := p.PurchaseService.GetPurchase(c.Request().Context(), idValue)
+	sort := model.PurchaseItemSort(c.QueryParam("sort"))
+	if len(sort) == 0 {
+		sort = model.PURCHASE_ITEM_SORT_WALKING
+	}
+
+	var products model.Purchase
+	switch c.QueryParam("groupBy") {
+	case "":
+		products, err = p.PurchaseService.GetPurchaseSorted(c.Request().Context(), idValue, sort)
+	case "category":
+		products, err = p.PurchaseService.GetPurchaseGroupedByCategory(c.Request().Context(), idValue, sort)
+	default:
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid groupBy"))
+	}
 
 	if err != nil {
-		var mkError *util.MarketListError
-		if errors.As(err, &mkError) && mkError.ErrorType == util.NOT_FOUND {
-			return handleError(c, http.StatusNotFound, mkError)
-		}
-		return handleError(c, http.StatusInternalServerError, err)
+		return handleServiceError(c, err)
 	}
 
 	purchase := controllerModel.Purchase{}
 	purchase.FromModel(products)
 
-	return c.JSON(http.StatusOK, purchase)
+	etag, err := contentETag(purchase)
+	if err != nil {
+		return handleError(c, http.StatusInternalServerError, util.MakeErrorUnknown(err))
+	}
+
+	return jsonWithETag(c, http.StatusOK, etag, purchase)
+}
+
+func parseQueryInt64(c echo.Context, name string) (*int64, error) {
+	value := c.QueryParam(name)
+	if len(value) == 0 {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("invalid %s", name))
+	}
+	return &parsed, nil
+}
+
+// parseQueryDate accepts RFC3339 timestamps or plain dates. A plain date used as an upper bound includes the whole day.
+func parseQueryDate(c echo.Context, name string, upperBound bool) (*time.Time, error) {
+	value := c.QueryParam(name)
+	if len(value) == 0 {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("invalid %s", name))
+	}
+	if upperBound {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return &parsed, nil
+}
+
+func parsePurchaseFilter(c echo.Context) (model.PurchaseFilter, error) {
+	var filter model.PurchaseFilter
+	var err error
+
+	if filter.MarketId, err = parseQueryInt64(c, "marketId"); err != nil {
+		return filter, err
+	}
+	if filter.TagId, err = parseQueryInt64(c, "tagId"); err != nil {
+		return filter, err
+	}
+	if favorite := c.QueryParam("favorite"); len(favorite) > 0 {
+		isFavorite, err := strconv.ParseBool(favorite)
+		if err != nil {
+			return filter, util.MakeError(util.INVALID_INPUT, "invalid favorite")
+		}
+		filter.IsFavorite = &isFavorite
+	}
+	if filter.CreatedFrom, err = parseQueryDate(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseQueryDate(c, "to", true); err != nil {
+		return filter, err
+	}
+
+	filter.Sort = model.PurchaseSort(c.QueryParam("sort"))
+	switch c.QueryParam("order") {
+	case "asc":
+		filter.Descending = false
+	case "desc":
+		filter.Descending = true
+	case "":
+		filter.Descending = filter.Sort != model.PURCHASE_SORT_NAME
+	default:
+		return filter, util.MakeError(util.INVALID_INPUT, "invalid order")
+	}
+
+	if limit := c.QueryParam("limit"); len(limit) > 0 {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return filter, util.MakeError(util.INVALID_INPUT, "invalid limit")
+		}
+	}
+	if cursor := c.QueryParam("cursor"); len(cursor) > 0 {
+		filter.Cursor = &cursor
+	}
+
+	return filter, nil
 }
 
 func (p PurchaseController) GetAllPurchase(c echo.Context) error {
+	filter, err := parsePurchaseFilter(c)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
 
-	purchases, err := p.PurchaseService.GetAllPurchase(c.Request().Context())
+	page, err := p.PurchaseService.GetAllPurchase(c.Request().Context(), filter)
 
 	if err != nil {
+		var mkError *util.MarketListError
+		if errors.As(err, &mkError) && mkError.ErrorType == util.INVALID_INPUT {
+			return handleError(c, http.StatusBadRequest, mkError)
+		}
 		return handleError(c, http.StatusInternalServerError, err)
 	}
 
-	purchasesFiltered := make([]controllerModel.Purchase, len(purchases), len(purchases))
-	for i, _ := range purchases {
-		purchasesFiltered[i].FromModel(purchases[i])
+	pageFiltered := controllerModel.PurchasePage{}
+	pageFiltered.FromModel(page)
+
+	return c.JSON(http.StatusOK, pageFiltered)
+}
+
+// UpdatePurchase only changes the fields present in the body.
+func (p PurchaseController) UpdatePurchase(c echo.Context) error {
+	return p.writePurchase(c, p.PurchaseService.UpdatePurchase)
+}
+
+// ReplacePurchase overwrites the metadata of the purchase, the fields missing from the body are cleared.
+func (p PurchaseController) ReplacePurchase(c echo.Context) error {
+	return p.writePurchase(c, p.PurchaseService.ReplacePurchase)
+}
+
+func (p PurchaseController) writePurchase(c echo.Context, write func(ctx context.Context, id int64, update model.PurchaseUpdate) (model.Purchase, error)) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
+	}
+
+	update := model.PurchaseUpdate{}
+	err = (&echo.DefaultBinder{}).BindBody(c, &update)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Update"))
+	}
+
+	purchase, err := write(c.Request().Context(), idValue, update)
+
+	if err != nil {
+		return handleServiceError(c, err)
 	}
 
-	return c.JSON(http.StatusOK, purchasesFiltered)
+	purchaseFiltered := controllerModel.Purchase{}
+	purchaseFiltered.FromModel(purchase)
+
+	return c.JSON(http.StatusOK, purchaseFiltered)
+}
+
+func (p PurchaseController) ClonePurchase(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
+	}
+
+	options := model.PurchaseCloneOptions{}
+	err = (&echo.DefaultBinder{}).BindBody(c, &options)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Clone options"))
+	}
+
+	purchase, err := p.PurchaseService.ClonePurchase(c.Request().Context(), idValue, options)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	purchaseFiltered := controllerModel.Purchase{}
+	purchaseFiltered.FromModel(purchase)
+
+	return c.JSON(http.StatusCreated, purchaseFiltered)
 }
 
 func (p PurchaseController) DeletePurchase(c echo.Context) error {
@@ -195,16 +457,83 @@ func (p PurchaseController) DeletePurchase(c echo.Context) error {
 	err = p.PurchaseService.DeletePurchase(c.Request().Context(), idValue)
 
 	if err != nil {
-		var mkError *util.MarketListError
-		if errors.As(err, &mkError) && mkError.ErrorType == util.NOT_FOUND {
-			return handleError(c, http.StatusNotFound, mkError)
-		}
-		return handleError(c, http.StatusInternalServerError, err)
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
+
+func (p PurchaseController) LeavePurchase(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
+	}
+
+	err = p.PurchaseService.LeavePurchase(c.Request().Context(), idValue)
+
+	if err != nil {
+		return handleServiceError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, nil)
 }
 
+func parsePurchaseUserParams(c echo.Context) (int64, int64, error) {
+	idValue, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id")
+	}
+
+	userIdValue, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		return 0, 0, util.MakeError(util.INVALID_INPUT, "invalid User Id")
+	}
+
+	return idValue, userIdValue, nil
+}
+
+func (p PurchaseController) UpdateParticipantRole(c echo.Context) error {
+	idValue, userIdValue, err := parsePurchaseUserParams(c)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	var user model.User
+	if err := (&echo.DefaultBinder{}).BindBody(c, &user); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	purchase, err := p.PurchaseService.UpdateParticipantRole(c.Request().Context(), idValue, userIdValue, user.Role)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	purchaseFiltered := controllerModel.Purchase{}
+	purchaseFiltered.FromModel(purchase)
+
+	return c.JSON(http.StatusOK, purchaseFiltered)
+}
+
+func (p PurchaseController) RemoveParticipant(c echo.Context) error {
+	idValue, userIdValue, err := parsePurchaseUserParams(c)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	purchase, err := p.PurchaseService.RemoveParticipant(c.Request().Context(), idValue, userIdValue)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	purchaseFiltered := controllerModel.Purchase{}
+	purchaseFiltered.FromModel(purchase)
+
+	return c.JSON(http.StatusOK, purchaseFiltered)
+}
+
 func (p PurchaseController) GetItem(c echo.Context) error {
 	id := c.Param("id")
 	idValue, err := strconv.ParseInt(id, 10, 64)
@@ -221,12 +550,109 @@ func (p PurchaseController) GetItem(c echo.Context) error {
 	item, err := p.PurchaseService.GetItem(c.Request().Context(), idValue, itemIdValue)
 
 	if err != nil {
-		var mkError *util.MarketListError
-		if errors.As(err, &mkError) && mkError.ErrorType == util.NOT_FOUND {
-			return handleError(c, http.StatusNotFound, mkError)
+		return handleServiceError(c, err)
+	}
+
+	return jsonWithETag(c, http.StatusOK, itemETag(item), item)
+}
+
+func writePurchaseEvent(c echo.Context, event model.PurchaseEvent) error {
+	eventFiltered := controllerModel.PurchaseEvent{}
+	eventFiltered.FromModel(event)
+
+	data, err := json.Marshal(eventFiltered)
+	if err != nil {
+		return err
+	}
+
+	response := c.Response()
+	if event.Id != 0 {
+		if _, err = fmt.Fprintf(response, "id: %d\n", event.Id); err != nil {
+			return err
 		}
-		return handleError(c, http.StatusInternalServerError, err)
+	}
+	if _, err = fmt.Fprintf(response, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	response.Flush()
+	return nil
+}
+
+// StreamEvents sends the changes of a purchase as Server-Sent Events. Reconnecting clients resume from the
+// Last-Event-ID header (or lastEventId query parameter) and receive a RESYNC event when the gap cannot be replayed.
+func (p PurchaseController) StreamEvents(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id"))
+	}
+
+	lastEventId := c.Request().Header.Get("Last-Event-ID")
+	if len(lastEventId) == 0 {
+		lastEventId = c.QueryParam("lastEventId")
+	}
+	var lastEventIdValue *int64
+	if len(lastEventId) > 0 {
+		value, err := strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil {
+			return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Last-Event-ID"))
+		}
+		lastEventIdValue = &value
 	}
 
-	return c.JSON(http.StatusOK, item)
+	ctx := c.Request().Context()
+	subscription, err := p.PurchaseService.SubscribeEvents(ctx, idValue, lastEventIdValue)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+	defer subscription.Close()
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	if subscription.Resync {
+		err = writePurchaseEvent(c, model.PurchaseEvent{Type: model.PURCHASE_EVENT_RESYNC, PurchaseId: idValue, CreatedAt: time.Now()})
+		if err != nil {
+			return nil
+		}
+	}
+	for _, event := range subscription.Replay {
+		if err = writePurchaseEvent(c, event); err != nil {
+			return nil
+		}
+	}
+
+	keepAlive := time.NewTicker(PURCHASE_EVENT_KEEP_ALIVE)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err = fmt.Fprint(response, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			response.Flush()
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return nil
+			}
+			if err = writePurchaseEvent(c, event); err != nil {
+				return nil
+			}
+			if event.Type == model.PURCHASE_EVENT_PURCHASE_DELETED {
+				return nil
+			}
+			if event.Type == model.PURCHASE_EVENT_PURCHASE_CHANGED {
+				if _, err = p.PurchaseService.GetPurchase(ctx, idValue); err != nil {
+					return nil
+				}
+			}
+		}
+	}
 }
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/service"
+	"net/http"
+)
+
+type ReportController struct {
+	ReportService service.ReportService
+}
+
+func CreateReportController(reportService service.ReportService) *ReportController {
+	return &ReportController{
+		ReportService: reportService,
+	}
+}
+
+func (r ReportController) Register(echo *echo.Echo) error {
+	report := echo.Group("/v1/reports")
+	report.GET("/inflation", r.GetInflationIndex)
+	report.GET("/spending", r.GetSpending)
+
+	return nil
+}
+
+func (r ReportController) GetInflationIndex(c echo.Context) error {
+	var filter model.InflationFilter
+	var err error
+
+	if filter.From, err = parseQueryDate(c, "from", false); err != nil {
+		return handleServiceError(c, err)
+	}
+	if filter.To, err = parseQueryDate(c, "to", true); err != nil {
+		return handleServiceError(c, err)
+	}
+
+	index, err := r.ReportService.GetInflationIndex(c.Request().Context(), filter)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, index)
+}
+
+func (r ReportController) GetSpending(c echo.Context) error {
+	filter := model.SpendingFilter{Granularity: model.SpendingGranularity(c.QueryParam("groupBy"))}
+	var err error
+
+	if filter.From, err = parseQueryDate(c, "from", false); err != nil {
+		return handleServiceError(c, err)
+	}
+	if filter.To, err = parseQueryDate(c, "to", true); err != nil {
+		return handleServiceError(c, err)
+	}
+
+	report, err := r.ReportService.GetSpending(c.Request().Context(), filter)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	controllerModel "github.com/ronistone/market-list/src/controller/model"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/service"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+)
+
+type SyncController struct {
+	SyncService service.SyncService
+}
+
+func CreateSyncController(syncService service.SyncService) *SyncController {
+	return &SyncController{
+		SyncService: syncService,
+	}
+}
+
+func (s SyncController) Register(echo *echo.Echo) error {
+	v1 := echo.Group("/v1/sync")
+	v1.POST("/", s.Sync)
+
+	return nil
+}
+
+func (s SyncController) Sync(c echo.Context) error {
+	var request model.SyncRequest
+	if err := (&echo.DefaultBinder{}).BindBody(c, &request); err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid sync request"))
+	}
+
+	result, err := s.SyncService.Sync(c.Request().Context(), request)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	resultFiltered := controllerModel.SyncResult{}
+	resultFiltered.FromModel(result)
+
+	return c.JSON(http.StatusOK, resultFiltered)
+}
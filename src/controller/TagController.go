@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	controllerModel "github.com/ronistone/market-list/src/controller/model"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/service"
+	"github.com/ronistone/market-list/src/util"
+	"net/http"
+	"strconv"
+)
+
+type TagController struct {
+	TagService service.TagService
+}
+
+func CreateTagController(tagService service.TagService) *TagController {
+	return &TagController{
+		TagService: tagService,
+	}
+}
+
+func (t TagController) Register(echo *echo.Echo) error {
+	v1 := echo.Group("/v1/tag")
+	v1.POST("/", t.CreateTag)
+	v1.PUT("/:id", t.UpdateTag)
+	v1.DELETE("/:id", t.DeleteTag)
+	v1.GET("/:id", t.GetTag)
+	v1.GET("/", t.GetAllTags)
+
+	purchase := echo.Group("/v1/purchase")
+	purchase.POST("/:id/tag/:tagId", t.AttachTag)
+	purchase.DELETE("/:id/tag/:tagId", t.DetachTag)
+
+	return nil
+}
+
+func (t TagController) CreateTag(c echo.Context) error {
+	var tag model.Tag
+
+	if err := c.Bind(&tag); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	tag, err := t.TagService.Create(c.Request().Context(), tag)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	tagFiltered := controllerModel.Tag{}
+	tagFiltered.FromModel(tag)
+
+	return c.JSON(http.StatusCreated, tagFiltered)
+}
+
+func (t TagController) UpdateTag(c echo.Context) error {
+	var tag model.Tag
+
+	if err := c.Bind(&tag); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Tag Id"))
+	}
+	tag.Id = idValue
+
+	tag, err = t.TagService.Update(c.Request().Context(), tag)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	tagFiltered := controllerModel.Tag{}
+	tagFiltered.FromModel(tag)
+
+	return c.JSON(http.StatusOK, tagFiltered)
+}
+
+func (t TagController) DeleteTag(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Tag Id"))
+	}
+
+	err = t.TagService.Delete(c.Request().Context(), idValue)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
+
+func (t TagController) GetTag(c echo.Context) error {
+	id := c.Param("id")
+	idValue, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, util.MakeError(util.INVALID_INPUT, "invalid Tag Id"))
+	}
+
+	tag, err := t.TagService.GetById(c.Request().Context(), idValue)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	tagFiltered := controllerModel.Tag{}
+	tagFiltered.FromModel(tag)
+
+	return c.JSON(http.StatusOK, tagFiltered)
+}
+
+func (t TagController) GetAllTags(c echo.Context) error {
+	tags, err := t.TagService.List(c.Request().Context())
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	tagsFiltered := make([]controllerModel.Tag, len(tags))
+	for i := range tags {
+		tagsFiltered[i].FromModel(tags[i])
+	}
+
+	return c.JSON(http.StatusOK, tagsFiltered)
+}
+
+func parsePurchaseTagParams(c echo.Context) (int64, int64, error) {
+	idValue, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, util.MakeError(util.INVALID_INPUT, "invalid Purchase Id")
+	}
+
+	tagIdValue, err := strconv.ParseInt(c.Param("tagId"), 10, 64)
+	if err != nil {
+		return 0, 0, util.MakeError(util.INVALID_INPUT, "invalid Tag Id")
+	}
+
+	return idValue, tagIdValue, nil
+}
+
+func (t TagController) AttachTag(c echo.Context) error {
+	idValue, tagIdValue, err := parsePurchaseTagParams(c)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	purchase, err := t.TagService.AttachToPurchase(c.Request().Context(), idValue, tagIdValue)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	purchaseFiltered := controllerModel.Purchase{}
+	purchaseFiltered.FromModel(purchase)
+
+	return c.JSON(http.StatusOK, purchaseFiltered)
+}
+
+func (t TagController) DetachTag(c echo.Context) error {
+	idValue, tagIdValue, err := parsePurchaseTagParams(c)
+	if err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	purchase, err := t.TagService.DetachFromPurchase(c.Request().Context(), idValue, tagIdValue)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	purchaseFiltered := controllerModel.Purchase{}
+	purchaseFiltered.FromModel(purchase)
+
+	return c.JSON(http.StatusOK, purchaseFiltered)
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	controllerModel "github.com/ronistone/market-list/src/controller/model"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/service"
+	"net/http"
+)
+
+type UserController struct {
+	UserService service.UserService
+}
+
+func CreateUserController(userService service.UserService) *UserController {
+	return &UserController{
+		UserService: userService,
+	}
+}
+
+func (u UserController) Register(echo *echo.Echo) error {
+	v1 := echo.Group("/v1/user")
+	v1.POST("/", u.CreateUser)
+	v1.GET("/me", u.GetCurrentUser)
+	v1.PUT("/me", u.UpdateCurrentUser)
+	v1.PUT("/me/password", u.ChangePassword)
+	v1.POST("/password/reset", u.RequestPasswordReset)
+	v1.POST("/password/reset/confirm", u.ConfirmPasswordReset)
+
+	return nil
+}
+
+func (u UserController) PublicRoutes() []string {
+	return []string{
+		http.MethodPost + " /v1/user/",
+		http.MethodPost + " /v1/user/password/reset",
+		http.MethodPost + " /v1/user/password/reset/confirm",
+	}
+}
+
+// CreateUser registers an account without signing in, /v1/auth/signup registers and returns a token.
+func (u UserController) CreateUser(c echo.Context) error {
+	var user model.User
+
+	if err := c.Bind(&user); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	user, err := u.UserService.CreateUser(c.Request().Context(), user)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	userFiltered := controllerModel.User{}
+	userFiltered.FromModel(user)
+
+	return c.JSON(http.StatusCreated, userFiltered)
+}
+
+func (u UserController) GetCurrentUser(c echo.Context) error {
+	user, err := u.UserService.GetCurrentUser(c.Request().Context())
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	userFiltered := controllerModel.User{}
+	userFiltered.FromModel(user)
+
+	return c.JSON(http.StatusOK, userFiltered)
+}
+
+func (u UserController) UpdateCurrentUser(c echo.Context) error {
+	var user model.User
+
+	if err := c.Bind(&user); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	user, err := u.UserService.UpdateCurrentUser(c.Request().Context(), user)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	userFiltered := controllerModel.User{}
+	userFiltered.FromModel(user)
+
+	return c.JSON(http.StatusOK, userFiltered)
+}
+
+func (u UserController) ChangePassword(c echo.Context) error {
+	var passwordChange model.PasswordChange
+
+	if err := c.Bind(&passwordChange); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	err := u.UserService.ChangePassword(c.Request().Context(), passwordChange)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (u UserController) RequestPasswordReset(c echo.Context) error {
+	var request model.PasswordResetRequest
+
+	if err := c.Bind(&request); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	err := u.UserService.RequestPasswordReset(c.Request().Context(), request)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (u UserController) ConfirmPasswordReset(c echo.Context) error {
+	var confirm model.PasswordResetConfirm
+
+	if err := c.Bind(&confirm); err != nil {
+		return handleError(c, http.StatusBadRequest, err)
+	}
+
+	err := u.UserService.ConfirmPasswordReset(c.Request().Context(), confirm)
+
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,13 @@
+package model
+
+import "github.com/ronistone/market-list/src/model"
+
+type Category struct {
+	Id   *int64 `json:"id"`
+	Name string `json:"name"`
+}
+
+func (c *Category) FromModel(categoryModel model.Category) {
+	c.Id = categoryModel.Id
+	c.Name = categoryModel.Name
+}
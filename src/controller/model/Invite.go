@@ -0,0 +1,29 @@
+package model
+
+import (
+	"github.com/ronistone/market-list/src/model"
+	"time"
+)
+
+type Invite struct {
+	Id        *int64     `json:"id"`
+	Purchase  Purchase   `json:"purchase"`
+	Inviter   User       `json:"inviter"`
+	Email     string     `json:"email"`
+	Status    string     `json:"status"`
+	Role      string     `json:"role"`
+	CreatedAt *time.Time `json:"createdAt"`
+}
+
+func (i *Invite) FromModel(inviteModel model.Invite) {
+	i.Id = inviteModel.Id
+	i.Purchase = Purchase{
+		Id:   inviteModel.Purchase.Id,
+		Name: inviteModel.Purchase.Name,
+	}
+	i.Inviter.FromModel(inviteModel.Inviter)
+	i.Email = inviteModel.Email
+	i.Status = string(inviteModel.Status)
+	i.Role = string(inviteModel.Role)
+	i.CreatedAt = inviteModel.CreatedAt
+}
@@ -5,11 +5,13 @@ import (
 )
 
 type Product struct {
-	Id   *int64  `json:"id"`
-	Ean  *string `json:"ean"`
-	Name string  `json:"name"`
-	Unit string  `json:"unit"`
-	Size int64   `json:"size"`
+	Id         *int64  `json:"id"`
+	Ean        *string `json:"ean"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	Size       int64   `json:"size"`
+	Version    int64   `json:"version"`
+	CategoryId *int64  `json:"categoryId"`
 }
 
 func (p *Product) FromModel(productModel model.Product) {
@@ -18,4 +20,6 @@ func (p *Product) FromModel(productModel model.Product) {
 	p.Name = productModel.Name
 	p.Unit = productModel.Unit
 	p.Size = productModel.Size
+	p.Version = productModel.Version
+	p.CategoryId = productModel.CategoryId
 }
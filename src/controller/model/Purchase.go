@@ -6,25 +6,66 @@ import (
 )
 
 type Purchase struct {
-	Id            *int64         `json:"id,omitempty"`
-	Name          string         `json:"name"`
-	User          []User         `json:"user,omitempty"`
-	Market        *Market        `json:"market,omitempty"`
-	CreatedAt     *time.Time     `json:"createdAt,omitempty"`
-	Items         []PurchaseItem `json:"items,omitempty"`
-	TotalSpent    int64          `json:"totalSpent"`
-	TotalExpected int64          `json:"totalExpected"`
-	IsFavorite    bool           `json:"isFavorite"`
-	Tags          []Tag          `json:"tags,omitempty"`
+	Id                 *int64              `json:"id,omitempty"`
+	Name               string              `json:"name"`
+	User               []User              `json:"user,omitempty"`
+	Market             *Market             `json:"market,omitempty"`
+	CreatedAt          *time.Time          `json:"createdAt,omitempty"`
+	Items              []PurchaseItem      `json:"items,omitempty"`
+	TotalSpent         int64               `json:"totalSpent"`
+	TotalExpected      int64               `json:"totalExpected"`
+	ItemCount          int64               `json:"itemCount"`
+	PurchasedItemCount int64               `json:"purchasedItemCount"`
+	IsFavorite         bool                `json:"isFavorite"`
+	Tags               []Tag               `json:"tags,omitempty"`
+	Role               string              `json:"role,omitempty"`
+	Groups             []PurchaseItemGroup `json:"groups,omitempty"`
+	TotalEstimated     *int64              `json:"totalEstimated,omitempty"`
+}
+
+type PurchaseItemGroup struct {
+	Category           *Category      `json:"category"`
+	Items              []PurchaseItem `json:"items"`
+	TotalSpent         int64          `json:"totalSpent"`
+	TotalExpected      int64          `json:"totalExpected"`
+	TotalEstimated     int64          `json:"totalEstimated"`
+	ItemCount          int64          `json:"itemCount"`
+	PurchasedItemCount int64          `json:"purchasedItemCount"`
+}
+
+func (g *PurchaseItemGroup) FromModel(groupModel model.PurchaseItemGroup) {
+	if groupModel.Category != nil {
+		category := Category{}
+		category.FromModel(*groupModel.Category)
+		g.Category = &category
+	}
+	g.Items = make([]PurchaseItem, len(groupModel.Items))
+	for i := range groupModel.Items {
+		g.Items[i].FromModel(groupModel.Items[i])
+	}
+	g.TotalSpent = groupModel.TotalSpent
+	g.TotalExpected = groupModel.TotalExpected
+	g.TotalEstimated = groupModel.TotalEstimated
+	g.ItemCount = groupModel.ItemCount
+	g.PurchasedItemCount = groupModel.PurchasedItemCount
 }
 
 type PurchaseItem struct {
-	Id        *int64     `json:"id"`
-	Product   Product    `json:"product"`
-	Purchased bool       `json:"purchased"`
-	Quantity  int        `json:"quantity"`
-	Price     *int64     `json:"price"`
-	CreatedAt *time.Time `json:"createdAt"`
+	Id                   *int64                     `json:"id"`
+	Product              Product                    `json:"product"`
+	Purchased            bool                       `json:"purchased"`
+	Quantity             int                        `json:"quantity"`
+	Price                *int64                     `json:"price"`
+	CreatedAt            *time.Time                 `json:"createdAt"`
+	Version              int64                      `json:"version"`
+	Notes                *string                    `json:"notes"`
+	Priority             int                        `json:"priority"`
+	Optional             bool                       `json:"optional"`
+	Position             int64                      `json:"position"`
+	SuggestedCategoryId  *int64                     `json:"suggestedCategoryId,omitempty"`
+	AisleId              *int64                     `json:"aisleId,omitempty"`
+	EstimatedPrice       *int64                     `json:"estimatedPrice,omitempty"`
+	EstimatedPriceSource *model.PriceEstimateSource `json:"estimatedPriceSource,omitempty"`
 }
 
 func (pi *PurchaseItem) FromModel(itemModel model.PurchaseItem) {
@@ -37,6 +78,19 @@ func (pi *PurchaseItem) FromModel(itemModel model.PurchaseItem) {
 	pi.Quantity = itemModel.Quantity
 	pi.Price = itemModel.Price
 	pi.CreatedAt = itemModel.CreatedAt
+	pi.Version = itemModel.Version
+	pi.Notes = itemModel.Notes
+	if itemModel.Priority != nil {
+		pi.Priority = *itemModel.Priority
+	}
+	if itemModel.Optional != nil {
+		pi.Optional = *itemModel.Optional
+	}
+	pi.Position = itemModel.Position
+	pi.SuggestedCategoryId = itemModel.SuggestedCategoryId
+	pi.AisleId = itemModel.AisleId
+	pi.EstimatedPrice = itemModel.EstimatedPrice
+	pi.EstimatedPriceSource = itemModel.EstimatedPriceSource
 }
 
 func (p *Purchase) FromModel(purchaseModel model.Purchase) {
@@ -46,11 +100,9 @@ func (p *Purchase) FromModel(purchaseModel model.Purchase) {
 	var users []User
 
 	for _, user := range purchaseModel.Users {
-		users = append(users, User{
-			Id:    *user.Id,
-			Name:  user.Name,
-			Email: user.Email,
-		})
+		userFiltered := User{}
+		userFiltered.FromModel(user)
+		users = append(users, userFiltered)
 	}
 
 	var tags []Tag
@@ -59,6 +111,7 @@ func (p *Purchase) FromModel(purchaseModel model.Purchase) {
 	}
 
 	p.User = users
+	p.Tags = tags
 
 	if purchaseModel.Market != nil {
 		market := Market{}
@@ -77,7 +130,71 @@ func (p *Purchase) FromModel(purchaseModel model.Purchase) {
 	}
 	p.TotalSpent = purchaseModel.TotalSpent
 	p.TotalExpected = purchaseModel.TotalExpected
+	p.TotalEstimated = purchaseModel.TotalEstimated
+	p.ItemCount = purchaseModel.ItemCount
+	p.PurchasedItemCount = purchaseModel.PurchasedItemCount
 	p.Name = purchaseModel.Name
 	p.IsFavorite = purchaseModel.IsFavorite
+	p.Role = string(purchaseModel.Role)
+
+	if purchaseModel.Groups != nil {
+		p.Groups = make([]PurchaseItemGroup, len(purchaseModel.Groups))
+		for i := range purchaseModel.Groups {
+			p.Groups[i].FromModel(purchaseModel.Groups[i])
+		}
+	}
+
+}
 
+type PurchaseEvent struct {
+	Id         int64         `json:"id"`
+	Type       string        `json:"type"`
+	PurchaseId int64         `json:"purchaseId"`
+	UserId     int64         `json:"userId"`
+	ItemId     *int64        `json:"itemId,omitempty"`
+	Item       *PurchaseItem `json:"item,omitempty"`
+	Purchase   *Purchase     `json:"purchase,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+}
+
+func (e *PurchaseEvent) FromModel(eventModel model.PurchaseEvent) {
+	e.Id = eventModel.Id
+	e.Type = string(eventModel.Type)
+	e.PurchaseId = eventModel.PurchaseId
+	e.UserId = eventModel.UserId
+	e.ItemId = eventModel.ItemId
+	if eventModel.Item != nil {
+		item := PurchaseItem{}
+		item.FromModel(*eventModel.Item)
+		e.Item = &item
+	}
+	if eventModel.Purchase != nil {
+		purchase := Purchase{}
+		purchase.FromModel(*eventModel.Purchase)
+		e.Purchase = &purchase
+	}
+	e.CreatedAt = eventModel.CreatedAt
+}
+
+type PurchaseItemBatchResult struct {
+	Purchase Purchase                            `json:"purchase"`
+	Results  []model.PurchaseItemOperationResult `json:"results"`
+}
+
+func (r *PurchaseItemBatchResult) FromModel(resultModel model.PurchaseItemBatchResult) {
+	r.Purchase.FromModel(resultModel.Purchase)
+	r.Results = resultModel.Results
+}
+
+type PurchasePage struct {
+	Items      []Purchase `json:"items"`
+	NextCursor *string    `json:"nextCursor"`
+}
+
+func (p *PurchasePage) FromModel(pageModel model.PurchasePage) {
+	p.Items = make([]Purchase, len(pageModel.Items))
+	for i := range pageModel.Items {
+		p.Items[i].FromModel(pageModel.Items[i])
+	}
+	p.NextCursor = pageModel.NextCursor
 }
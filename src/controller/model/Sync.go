@@ -0,0 +1,38 @@
+package model
+
+import (
+	"github.com/ronistone/market-list/src/model"
+)
+
+type SyncItem struct {
+	PurchaseItem
+	PurchaseId *int64 `json:"purchaseId"`
+}
+
+type SyncResult struct {
+	SyncToken  string                     `json:"syncToken"`
+	FullSync   bool                       `json:"fullSync"`
+	Purchases  []Purchase                 `json:"purchases"`
+	Items      []SyncItem                 `json:"items"`
+	Tombstones []model.SyncTombstone      `json:"tombstones"`
+	Results    []model.SyncMutationResult `json:"results"`
+}
+
+func (s *SyncResult) FromModel(resultModel model.SyncResult) {
+	s.SyncToken = resultModel.SyncToken
+	s.FullSync = resultModel.FullSync
+
+	s.Purchases = make([]Purchase, len(resultModel.Purchases))
+	for i := range resultModel.Purchases {
+		s.Purchases[i].FromModel(resultModel.Purchases[i])
+	}
+
+	s.Items = make([]SyncItem, len(resultModel.Items))
+	for i, item := range resultModel.Items {
+		s.Items[i].FromModel(item)
+		s.Items[i].PurchaseId = item.PurchaseId
+	}
+
+	s.Tombstones = resultModel.Tombstones
+	s.Results = resultModel.Results
+}
@@ -1,11 +1,23 @@
 package model
 
-import "time"
+import (
+	"github.com/ronistone/market-list/src/model"
+	"time"
+)
 
 type Tag struct {
 	Id        int64      `json:"id"`
 	Name      string     `json:"name"`
-	UserId    int64      `json:"userid"`
-	Purchases []Purchase `json:"purchases"`
-	CreatedAt *time.Time `json:"createdAt"`
+	UserId    int64      `json:"userid,omitempty"`
+	Purchases []Purchase `json:"purchases,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+func (t *Tag) FromModel(tagModel model.Tag) {
+	t.Id = tagModel.Id
+	t.Name = tagModel.Name
+	if tagModel.User.Id != nil {
+		t.UserId = *tagModel.User.Id
+	}
+	t.CreatedAt = tagModel.CreatedAt
 }
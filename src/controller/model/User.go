@@ -1,7 +1,21 @@
 package model
 
+import "github.com/ronistone/market-list/src/model"
+
 type User struct {
-	Id    int64  `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	Id       int64  `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Role     string `json:"role,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+func (u *User) FromModel(userModel model.User) {
+	if userModel.Id != nil {
+		u.Id = *userModel.Id
+	}
+	u.Name = userModel.Name
+	u.Email = userModel.Email
+	u.Role = string(userModel.Role)
+	u.Timezone = userModel.Timezone
 }
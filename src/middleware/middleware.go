@@ -1,11 +1,38 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/ronistone/market-list/src/service"
 	"github.com/ronistone/market-list/src/util"
+	"net/http"
+	"strings"
 )
 
+// redactedQueryParams are the query parameters whose values never reach the request log.
+var redactedQueryParams = []string{"access_token"}
+
+// Logger logs the requests like the echo logger, with the credentials sent in the query string redacted.
+func Logger() echo.MiddlewareFunc {
+	config := middleware.DefaultLoggerConfig
+	config.Format = strings.Replace(config.Format, "${uri}", "${custom}", 1)
+	config.CustomTagFunc = func(c echo.Context, buf *bytes.Buffer) (int, error) {
+		url := *c.Request().URL
+		query := url.Query()
+		for _, name := range redactedQueryParams {
+			if query.Has(name) {
+				query.Set(name, "REDACTED")
+			}
+		}
+		url.RawQuery = query.Encode()
+		return buf.WriteString(url.RequestURI())
+	}
+	return middleware.LoggerWithConfig(config)
+}
+
 func InjectLogger(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Request().Context()
@@ -15,12 +42,43 @@ func InjectLogger(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
-func InjectUserId(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		var userId int64 = 1
-		ctx := c.Request().Context()
-		request := c.Request()
-		c.SetRequest(request.WithContext(context.WithValue(ctx, "USER_ID", &userId)))
-		return next(c)
+// InjectUserId validates the bearer token of the request and puts the authenticated user id into the context.
+// Routes listed in publicRoutes (as "METHOD /path") skip the validation. Event streams may send the token in the
+// access_token query parameter, since browsers cannot set headers on EventSource requests.
+func InjectUserId(authService service.AuthService, publicRoutes ...string) echo.MiddlewareFunc {
+	public := make(map[string]bool, len(publicRoutes))
+	for _, route := range publicRoutes {
+		public[route] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request := c.Request()
+			if public[request.Method+" "+c.Path()] {
+				return next(c)
+			}
+
+			authorization := request.Header.Get(echo.HeaderAuthorization)
+			token, found := strings.CutPrefix(authorization, "Bearer ")
+			if !found && strings.Contains(request.Header.Get(echo.HeaderAccept), "text/event-stream") {
+				token, found = c.QueryParam("access_token"), true
+			}
+			if !found || len(token) == 0 {
+				return c.JSON(http.StatusUnauthorized, util.MakeError(util.UNAUTHORIZED, "missing bearer token"))
+			}
+
+			ctx := request.Context()
+			userId, err := authService.Authenticate(ctx, token)
+			if err != nil {
+				var mkError *util.MarketListError
+				if errors.As(err, &mkError) && mkError.ErrorType == util.UNAUTHORIZED {
+					return c.JSON(http.StatusUnauthorized, err)
+				}
+				return c.JSON(http.StatusInternalServerError, err)
+			}
+
+			c.SetRequest(request.WithContext(context.WithValue(ctx, "USER_ID", &userId)))
+			return next(c)
+		}
 	}
 }
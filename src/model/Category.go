@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+type Category struct {
+	Id        *int64     `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// PurchaseItemGroup holds the items of a purchase that share a category, with their subtotals.
+// Category is nil for the items no category could be found or suggested for.
+type PurchaseItemGroup struct {
+	Category           *Category      `json:"category"`
+	Items              []PurchaseItem `json:"items"`
+	TotalSpent         int64          `json:"totalSpent"`
+	TotalExpected      int64          `json:"totalExpected"`
+	TotalEstimated     int64          `json:"totalEstimated"`
+	ItemCount          int64          `json:"itemCount"`
+	PurchasedItemCount int64          `json:"purchasedItemCount"`
+}
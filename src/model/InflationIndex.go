@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+type InflationFilter struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// MonthlyPrice sums what the user paid for a product in a month at a market, and in purchases with a tag when TagId
+// is set. Spent divided by Quantity is the average unit price.
+type MonthlyPrice struct {
+	Month        time.Time `db:"month"`
+	ProductId    int64     `db:"product_id"`
+	ProductName  string    `db:"product_name"`
+	MarketId     int64     `db:"market_id"`
+	MarketName   string    `db:"market_name"`
+	CategoryId   *int64    `db:"category_id"`
+	CategoryName *string   `db:"category_name"`
+	TagId        *int64    `db:"tag_id"`
+	TagName      *string   `db:"tag_name"`
+	Spent        int64     `db:"spent"`
+	Quantity     int64     `db:"quantity"`
+}
+
+// BasketProduct is a product bought often enough to be in the basket. Weight is its share of the basket spending.
+type BasketProduct struct {
+	ProductId   int64   `json:"productId"`
+	ProductName string  `json:"productName"`
+	Months      int     `json:"months"`
+	Weight      float64 `json:"weight"`
+}
+
+// InflationPoint is the index of a month, 100 being the first month of the series. Change is the variation from the
+// previous month in percent, and Coverage the share of the basket weight that could be compared with it.
+type InflationPoint struct {
+	Month    time.Time `json:"month"`
+	Index    float64   `json:"index"`
+	Change   float64   `json:"change"`
+	Coverage float64   `json:"coverage"`
+}
+
+// InflationSeries holds the monthly points and the inflation in percent between the first and the last month.
+type InflationSeries struct {
+	Points    []InflationPoint `json:"points"`
+	Inflation float64          `json:"inflation"`
+}
+
+type MarketInflation struct {
+	MarketId   int64  `json:"marketId"`
+	MarketName string `json:"marketName"`
+	InflationSeries
+}
+
+// CategoryInflation has no CategoryId for the products without a category.
+type CategoryInflation struct {
+	CategoryId   *int64  `json:"categoryId"`
+	CategoryName *string `json:"categoryName"`
+	InflationSeries
+}
+
+type TagInflation struct {
+	TagId   int64  `json:"tagId"`
+	TagName string `json:"tagName"`
+	InflationSeries
+}
+
+// InflationIndex cuts the months in Timezone, the timezone of the user.
+type InflationIndex struct {
+	Timezone   string              `json:"timezone"`
+	From       *time.Time          `json:"from"`
+	To         *time.Time          `json:"to"`
+	Basket     []BasketProduct     `json:"basket"`
+	Overall    InflationSeries     `json:"overall"`
+	Markets    []MarketInflation   `json:"markets"`
+	Categories []CategoryInflation `json:"categories"`
+	Tags       []TagInflation      `json:"tags"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+type InviteStatus string
+
+const (
+	INVITE_PENDING  InviteStatus = "PENDING"
+	INVITE_ACCEPTED InviteStatus = "ACCEPTED"
+	INVITE_DECLINED InviteStatus = "DECLINED"
+)
+
+type Invite struct {
+	Id        *int64       `json:"id"`
+	Purchase  Purchase     `json:"purchase"`
+	Inviter   User         `json:"inviter"`
+	Email     string       `json:"email"`
+	Status    InviteStatus `json:"status"`
+	Role      PurchaseRole `json:"role"`
+	CreatedAt *time.Time   `json:"createdAt"`
+	UpdatedAt *time.Time   `json:"updatedAt"`
+}
@@ -0,0 +1,22 @@
+package model
+
+// MarketAisle is one stop of the walk through a market. Position orders the aisles of the market from the entrance.
+type MarketAisle struct {
+	Id          *int64  `json:"id"`
+	Name        string  `json:"name"`
+	Position    int     `json:"position"`
+	CategoryIds []int64 `json:"categoryIds"`
+	ProductIds  []int64 `json:"productIds"`
+}
+
+type MarketLayout struct {
+	MarketId int64         `json:"marketId"`
+	Aisles   []MarketAisle `json:"aisles"`
+}
+
+// MarketCheckOrder is what past purchases at a market tell about its walking order. Positions go from 0, checked off
+// first, to 1, checked off last, averaged over the purchases the product or the products of the aisle appear in.
+type MarketCheckOrder struct {
+	Products map[int64]float64
+	Aisles   map[int64]float64
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// MarketItemEstimate prices an item of the purchase with the last price paid for its product at a market.
+type MarketItemEstimate struct {
+	ItemId     int64     `json:"itemId"`
+	ProductId  int64     `json:"productId"`
+	Quantity   int       `json:"quantity"`
+	UnitPrice  int64     `json:"unitPrice"`
+	Total      int64     `json:"total"`
+	ObservedAt time.Time `json:"observedAt"`
+}
+
+// MarketEstimate is what the purchase would cost at a market. Total only covers the priced items, the items never
+// bought there are listed in MissingItemIds.
+type MarketEstimate struct {
+	Market         Market               `json:"market"`
+	Total          int64                `json:"total"`
+	Items          []MarketItemEstimate `json:"items"`
+	MissingItemIds []int64              `json:"missingItemIds"`
+}
+
+// MarketSplit buys every item at the cheaper of two markets. Each estimate only holds the items bought at its market.
+type MarketSplit struct {
+	Markets        []MarketEstimate `json:"markets"`
+	Total          int64            `json:"total"`
+	MissingItemIds []int64          `json:"missingItemIds"`
+}
+
+// MarketRecommendation compares the enabled markets for the items still to buy. Markets are sorted from the one
+// pricing the most items to the one pricing the fewest, and then from the cheapest to the most expensive.
+type MarketRecommendation struct {
+	PurchaseId       int64            `json:"purchaseId"`
+	Markets          []MarketEstimate `json:"markets"`
+	CheapestMarketId *int64           `json:"cheapestMarketId"`
+	Split            *MarketSplit     `json:"split,omitempty"`
+}
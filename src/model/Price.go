@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// PriceObservation is the price paid for a product in a purchase at a market, observed when the item was checked off.
+type PriceObservation struct {
+	ProductId  int64     `json:"-" db:"product_id"`
+	MarketId   int64     `json:"-" db:"market_id"`
+	MarketName string    `json:"-" db:"market_name"`
+	PurchaseId int64     `json:"purchaseId" db:"purchase_id"`
+	ItemId     int64     `json:"-" db:"purchase_item_id"`
+	Price      int64     `json:"price" db:"price"`
+	Quantity   int       `json:"quantity" db:"quantity"`
+	ObservedAt time.Time `json:"observedAt" db:"observed_at"`
+}
+
+type PriceFilter struct {
+	From *time.Time
+	To   *time.Time
+}
+
+type MarketPriceHistory struct {
+	Market         Market             `json:"market"`
+	Prices         []PriceObservation `json:"prices"`
+	Min            int64              `json:"min"`
+	Max            int64              `json:"max"`
+	Average        int64              `json:"average"`
+	Last           int64              `json:"last"`
+	LastObservedAt time.Time          `json:"lastObservedAt"`
+}
+
+type ProductPriceHistory struct {
+	Product Product              `json:"product"`
+	From    *time.Time           `json:"from"`
+	To      *time.Time           `json:"to"`
+	Markets []MarketPriceHistory `json:"markets"`
+}
+
+type PriceEstimateSourceType string
+
+const (
+	PRICE_ESTIMATE_SOURCE_PURCHASE_MARKET PriceEstimateSourceType = "PURCHASE_MARKET"
+	PRICE_ESTIMATE_SOURCE_OTHER_MARKET    PriceEstimateSourceType = "OTHER_MARKET"
+)
+
+// PriceEstimateSource tells where the estimated price of an item comes from: the last price paid for the product at
+// the market of the purchase or, when it was never bought there, at any other market.
+type PriceEstimateSource struct {
+	Type       PriceEstimateSourceType `json:"type"`
+	MarketId   int64                   `json:"marketId"`
+	MarketName string                  `json:"marketName"`
+	PurchaseId int64                   `json:"purchaseId"`
+	ObservedAt time.Time               `json:"observedAt"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// ProductWatch asks for an alert when a price is recorded for the product in one of the user's purchases and it is
+// lower than PriceBelow, or higher than the trailing average by more than SpikePercent. The product can be given by EAN.
+type ProductWatch struct {
+	Id           *int64     `json:"id"`
+	UserId       int64      `json:"-" db:"user_id"`
+	ProductId    *int64     `json:"productId" db:"product_id"`
+	Ean          *string    `json:"ean,omitempty" db:"-"`
+	PriceBelow   *int64     `json:"priceBelow" db:"price_below"`
+	SpikePercent *int       `json:"spikePercent" db:"spike_percent"`
+	CreatedAt    *time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt    *time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type PriceAlertType string
+
+const (
+	PRICE_ALERT_DROP  PriceAlertType = "PRICE_DROP"
+	PRICE_ALERT_SPIKE PriceAlertType = "PRICE_SPIKE"
+)
+
+// PriceAlert is an entry of the alert inbox. ReferencePrice is the PriceBelow threshold for drops and the trailing
+// average for spikes.
+type PriceAlert struct {
+	Id             *int64         `json:"id"`
+	UserId         int64          `json:"-" db:"user_id"`
+	WatchId        int64          `json:"watchId" db:"watch_id"`
+	ProductId      int64          `json:"productId" db:"product_id"`
+	MarketId       *int64         `json:"marketId" db:"market_id"`
+	PurchaseId     *int64         `json:"purchaseId" db:"purchase_id"`
+	PurchaseItemId *int64         `json:"purchaseItemId" db:"purchase_item_id"`
+	Type           PriceAlertType `json:"type"`
+	Price          int64          `json:"price"`
+	ReferencePrice int64          `json:"referencePrice" db:"reference_price"`
+	CreatedAt      *time.Time     `json:"createdAt" db:"created_at"`
+	ReadAt         *time.Time     `json:"readAt" db:"read_at"`
+}
@@ -2,26 +2,96 @@ package model
 
 import "time"
 
+type PurchaseRole string
+
+const (
+	PURCHASE_ROLE_OWNER  PurchaseRole = "OWNER"
+	PURCHASE_ROLE_EDITOR PurchaseRole = "EDITOR"
+	PURCHASE_ROLE_VIEWER PurchaseRole = "VIEWER"
+)
+
+func (r PurchaseRole) IsValid() bool {
+	return r == PURCHASE_ROLE_OWNER || r == PURCHASE_ROLE_EDITOR || r == PURCHASE_ROLE_VIEWER
+}
+
 type Purchase struct {
-	Id            *int64         `json:"id"`
-	Name          string         `json:"name"`
-	Users         []User         `json:"users"`
-	Market        *Market        `json:"market"`
-	CreatedAt     *time.Time     `json:"createdAt"`
-	Items         []PurchaseItem `json:"items"`
-	MarketId      *int64         `json:"marketId"`
-	TotalSpent    int64          `json:"totalSpent"`
-	TotalExpected int64          `json:"totalExpected"`
-	IsFavorite    bool           `json:"isFavorite"`
-	Tags          []Tag          `json:"tags"`
+	Id                 *int64              `json:"id"`
+	Name               string              `json:"name"`
+	Users              []User              `json:"users"`
+	Market             *Market             `json:"market"`
+	CreatedAt          *time.Time          `json:"createdAt"`
+	Items              []PurchaseItem      `json:"items"`
+	MarketId           *int64              `json:"marketId"`
+	TotalSpent         int64               `json:"totalSpent"`
+	TotalExpected      int64               `json:"totalExpected"`
+	ItemCount          int64               `json:"itemCount"`
+	PurchasedItemCount int64               `json:"purchasedItemCount"`
+	IsFavorite         bool                `json:"isFavorite"`
+	Tags               []Tag               `json:"tags"`
+	Role               PurchaseRole        `json:"role"`
+	Groups             []PurchaseItemGroup `json:"groups"`
+	// TotalEstimated is TotalExpected with the estimated prices of the unpriced items. It is only set when the
+	// items are fetched.
+	TotalEstimated *int64 `json:"totalEstimated"`
 }
 
+const (
+	PURCHASE_ITEM_PRIORITY_LOW    = 0
+	PURCHASE_ITEM_PRIORITY_NORMAL = 1
+	PURCHASE_ITEM_PRIORITY_HIGH   = 2
+	PURCHASE_ITEM_PRIORITY_URGENT = 3
+)
+
+const MAX_PURCHASE_ITEM_NOTES_LENGTH = 500
+
+// PurchaseItem keeps Notes, Priority and Optional as pointers so updates that leave them out do not reset them.
 type PurchaseItem struct {
-	Id        *int64     `json:"id"`
-	Purchase  *Purchase  `json:"purchase"`
-	Product   Product    `json:"product"`
-	Purchased bool       `json:"purchased"`
-	Quantity  int        `json:"quantity"`
-	Price     *int64     `json:"price"`
-	CreatedAt *time.Time `json:"createdAt"`
+	Id         *int64     `json:"id"`
+	Purchase   *Purchase  `json:"purchase"`
+	PurchaseId *int64     `json:"purchaseId"`
+	Product    Product    `json:"product"`
+	Purchased  bool       `json:"purchased"`
+	Quantity   int        `json:"quantity"`
+	Price      *int64     `json:"price"`
+	CreatedAt  *time.Time `json:"createdAt"`
+	Version    int64      `json:"version"`
+	Notes      *string    `json:"notes"`
+	Priority   *int       `json:"priority"`
+	Optional   *bool      `json:"optional"`
+	Position   int64      `json:"position"`
+	// SuggestedCategoryId is only set on grouped purchases, for items whose product has no category.
+	SuggestedCategoryId *int64 `json:"suggestedCategoryId"`
+	// AisleId is only set on purchases sorted in the walking order of their market.
+	AisleId *int64 `json:"aisleId"`
+	// EstimatedPrice is only set for items without a Price, when the product was bought before.
+	EstimatedPrice       *int64               `json:"estimatedPrice"`
+	EstimatedPriceSource *PriceEstimateSource `json:"estimatedPriceSource"`
+}
+
+type PurchaseItemSort string
+
+const (
+	PURCHASE_ITEM_SORT_DEFAULT PurchaseItemSort = "default"
+	PURCHASE_ITEM_SORT_MANUAL  PurchaseItemSort = "manual"
+	PURCHASE_ITEM_SORT_WALKING PurchaseItemSort = "walking"
+)
+
+// PurchaseItemMove places an item right before or right after another item of the same purchase.
+type PurchaseItemMove struct {
+	Before *int64 `json:"before"`
+	After  *int64 `json:"after"`
+}
+
+type PurchaseUpdate struct {
+	Name       *string `json:"name"`
+	MarketId   *int64  `json:"marketId"`
+	IsFavorite *bool   `json:"isFavorite"`
+}
+
+type PurchaseCloneOptions struct {
+	Name             *string `json:"name"`
+	KeepPrices       bool    `json:"keepPrices"`
+	KeepMarket       bool    `json:"keepMarket"`
+	KeepTags         bool    `json:"keepTags"`
+	KeepParticipants bool    `json:"keepParticipants"`
 }
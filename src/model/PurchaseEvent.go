@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+type PurchaseEventType string
+
+const (
+	PURCHASE_EVENT_ITEM_ADDED       PurchaseEventType = "ITEM_ADDED"
+	PURCHASE_EVENT_ITEM_REMOVED     PurchaseEventType = "ITEM_REMOVED"
+	PURCHASE_EVENT_ITEM_UPDATED     PurchaseEventType = "ITEM_UPDATED"
+	PURCHASE_EVENT_PURCHASE_CHANGED PurchaseEventType = "PURCHASE_CHANGED"
+	PURCHASE_EVENT_PURCHASE_DELETED PurchaseEventType = "PURCHASE_DELETED"
+	PURCHASE_EVENT_RESYNC           PurchaseEventType = "RESYNC"
+)
+
+type PurchaseEvent struct {
+	Id         int64             `json:"id"`
+	Type       PurchaseEventType `json:"type"`
+	PurchaseId int64             `json:"purchaseId"`
+	UserId     int64             `json:"userId"`
+	ItemId     *int64            `json:"itemId"`
+	Item       *PurchaseItem     `json:"item"`
+	Purchase   *Purchase         `json:"purchase"`
+	CreatedAt  time.Time         `json:"createdAt"`
+}
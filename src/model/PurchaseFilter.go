@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+type PurchaseSort string
+
+const (
+	PURCHASE_SORT_CREATED_AT PurchaseSort = "createdAt"
+	PURCHASE_SORT_NAME       PurchaseSort = "name"
+	PURCHASE_SORT_TOTAL      PurchaseSort = "total"
+)
+
+type PurchaseFilter struct {
+	MarketId    *int64
+	TagId       *int64
+	IsFavorite  *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        PurchaseSort
+	Descending  bool
+	Limit       int
+	Cursor      *string
+}
+
+type PurchasePage struct {
+	Items      []Purchase `json:"items"`
+	NextCursor *string    `json:"nextCursor"`
+}
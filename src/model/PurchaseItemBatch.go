@@ -0,0 +1,50 @@
+package model
+
+type PurchaseItemOperationType string
+
+const (
+	PURCHASE_ITEM_OPERATION_ADD    PurchaseItemOperationType = "ADD"
+	PURCHASE_ITEM_OPERATION_UPDATE PurchaseItemOperationType = "UPDATE"
+	PURCHASE_ITEM_OPERATION_REMOVE PurchaseItemOperationType = "REMOVE"
+	PURCHASE_ITEM_OPERATION_CHECK  PurchaseItemOperationType = "CHECK"
+)
+
+type PurchaseItemOperationStatus string
+
+const (
+	PURCHASE_ITEM_OPERATION_APPLIED     PurchaseItemOperationStatus = "APPLIED"
+	PURCHASE_ITEM_OPERATION_FAILED      PurchaseItemOperationStatus = "FAILED"
+	PURCHASE_ITEM_OPERATION_ROLLED_BACK PurchaseItemOperationStatus = "ROLLED_BACK"
+)
+
+// PurchaseItemOperation is one step of a batch. ADD and UPDATE carry the Item, REMOVE and CHECK only the ItemId.
+// CHECK marks the item as purchased unless Purchased is false. Version makes UPDATE and CHECK conditional.
+// ProductChanged is set while preparing ADD and UPDATE when their product has to be created or updated.
+type PurchaseItemOperation struct {
+	Type           PurchaseItemOperationType `json:"type"`
+	ItemId         *int64                    `json:"itemId"`
+	Item           *PurchaseItem             `json:"item"`
+	Purchased      *bool                     `json:"purchased"`
+	Version        *int64                    `json:"version"`
+	ProductChanged bool                      `json:"-"`
+}
+
+// PurchaseItemBatch applies all operations in one transaction, including the products written by ADD and UPDATE.
+// When Atomic is set a single failure rolls back the whole batch, otherwise only the failed operations are skipped.
+type PurchaseItemBatch struct {
+	Operations []PurchaseItemOperation `json:"operations"`
+	Atomic     bool                    `json:"atomic"`
+}
+
+type PurchaseItemOperationResult struct {
+	Index  int                         `json:"index"`
+	Type   PurchaseItemOperationType   `json:"type"`
+	Status PurchaseItemOperationStatus `json:"status"`
+	ItemId *int64                      `json:"itemId"`
+	Error  *string                     `json:"error"`
+}
+
+type PurchaseItemBatchResult struct {
+	Purchase Purchase                      `json:"purchase"`
+	Results  []PurchaseItemOperationResult `json:"results"`
+}
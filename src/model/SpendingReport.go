@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+type SpendingGranularity string
+
+const (
+	SPENDING_GRANULARITY_DAY   SpendingGranularity = "day"
+	SPENDING_GRANULARITY_WEEK  SpendingGranularity = "week"
+	SPENDING_GRANULARITY_MONTH SpendingGranularity = "month"
+	SPENDING_GRANULARITY_YEAR  SpendingGranularity = "year"
+)
+
+func (g SpendingGranularity) IsValid() bool {
+	return g == SPENDING_GRANULARITY_DAY || g == SPENDING_GRANULARITY_WEEK ||
+		g == SPENDING_GRANULARITY_MONTH || g == SPENDING_GRANULARITY_YEAR
+}
+
+// SpendingFilter bounds are compared with the creation time of the purchases in the timezone of the user.
+type SpendingFilter struct {
+	From        *time.Time
+	To          *time.Time
+	Granularity SpendingGranularity
+}
+
+// SpendingPeriod sums the purchases created in a period. Period is the first day of it, weeks starting on Monday.
+// A trip is a purchase with at least one purchased item, and the averages are by trip.
+type SpendingPeriod struct {
+	Period            string  `json:"period,omitempty" db:"period"`
+	TotalSpent        int64   `json:"totalSpent" db:"total_spent"`
+	TotalExpected     int64   `json:"totalExpected" db:"total_expected"`
+	PurchaseCount     int64   `json:"purchaseCount" db:"purchase_count"`
+	Trips             int64   `json:"trips" db:"trips"`
+	PurchasedQuantity int64   `json:"-" db:"purchased_quantity"`
+	AverageSpent      int64   `json:"averageSpent" db:"-"`
+	AverageItemCount  float64 `json:"averageItemCount" db:"-"`
+}
+
+type SpendingReport struct {
+	Granularity SpendingGranularity `json:"granularity"`
+	Timezone    string              `json:"timezone"`
+	From        *time.Time          `json:"from"`
+	To          *time.Time          `json:"to"`
+	Periods     []SpendingPeriod    `json:"periods"`
+	Total       SpendingPeriod      `json:"total"`
+}
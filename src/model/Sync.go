@@ -0,0 +1,84 @@
+package model
+
+import "time"
+
+type SyncEntity string
+
+const (
+	SYNC_ENTITY_PURCHASE      SyncEntity = "PURCHASE"
+	SYNC_ENTITY_PURCHASE_ITEM SyncEntity = "PURCHASE_ITEM"
+	SYNC_ENTITY_PURCHASE_USER SyncEntity = "PURCHASE_USER"
+)
+
+type SyncMutationType string
+
+const (
+	SYNC_MUTATION_ADD_ITEM        SyncMutationType = "ADD_ITEM"
+	SYNC_MUTATION_UPDATE_ITEM     SyncMutationType = "UPDATE_ITEM"
+	SYNC_MUTATION_REMOVE_ITEM     SyncMutationType = "REMOVE_ITEM"
+	SYNC_MUTATION_UPDATE_PURCHASE SyncMutationType = "UPDATE_PURCHASE"
+)
+
+type SyncMutationStatus string
+
+const (
+	SYNC_MUTATION_APPLIED  SyncMutationStatus = "APPLIED"
+	SYNC_MUTATION_PARTIAL  SyncMutationStatus = "PARTIAL"
+	SYNC_MUTATION_CONFLICT SyncMutationStatus = "CONFLICT"
+	SYNC_MUTATION_REJECTED SyncMutationStatus = "REJECTED"
+)
+
+type PurchaseItemChanges struct {
+	Purchased *bool  `json:"purchased"`
+	Quantity  *int   `json:"quantity"`
+	Price     *int64 `json:"price"`
+}
+
+type SyncMutation struct {
+	ClientId        string               `json:"clientId"`
+	Type            SyncMutationType     `json:"type"`
+	PurchaseId      int64                `json:"purchaseId"`
+	ItemId          *int64               `json:"itemId"`
+	MutatedAt       *time.Time           `json:"mutatedAt"`
+	Item            *PurchaseItem        `json:"item"`
+	ItemChanges     *PurchaseItemChanges `json:"itemChanges"`
+	PurchaseChanges *PurchaseUpdate      `json:"purchaseChanges"`
+}
+
+type SyncMutationResult struct {
+	ClientId  string             `json:"clientId"`
+	Status    SyncMutationStatus `json:"status"`
+	ItemId    *int64             `json:"itemId"`
+	Applied   []string           `json:"applied"`
+	Conflicts []string           `json:"conflicts"`
+	Error     *string            `json:"error"`
+}
+
+type SyncRequest struct {
+	SyncToken *string        `json:"syncToken"`
+	Mutations []SyncMutation `json:"mutations"`
+}
+
+// SyncChange is an entity touched since the last sync, as recorded by the change log.
+// OwnMembership marks PURCHASE_USER changes about the syncing user itself.
+type SyncChange struct {
+	Entity        SyncEntity
+	EntityId      int64
+	PurchaseId    int64
+	OwnMembership bool
+}
+
+type SyncTombstone struct {
+	Entity     SyncEntity `json:"entity"`
+	Id         int64      `json:"id"`
+	PurchaseId int64      `json:"purchaseId"`
+}
+
+type SyncResult struct {
+	SyncToken  string               `json:"syncToken"`
+	FullSync   bool                 `json:"fullSync"`
+	Purchases  []Purchase           `json:"purchases"`
+	Items      []PurchaseItem       `json:"items"`
+	Tombstones []SyncTombstone      `json:"tombstones"`
+	Results    []SyncMutationResult `json:"results"`
+}
@@ -3,10 +3,14 @@ package model
 import "time"
 
 type User struct {
-	Id        *int64     `json:"id"`
-	Email     string     `json:"email"`
-	Name      string     `json:"name"`
-	Password  *string    `json:"password"`
-	CreatedAt *time.Time `json:"createdAt"`
-	UpdatedAt *time.Time `json:"updatedAt"`
+	Id           *int64       `json:"id"`
+	Email        string       `json:"email"`
+	Name         string       `json:"name"`
+	Password     *string      `json:"password,omitempty"`
+	CreatedAt    *time.Time   `json:"createdAt"`
+	UpdatedAt    *time.Time   `json:"updatedAt"`
+	Role         PurchaseRole `json:"role,omitempty"`
+	Timezone     string       `json:"timezone"`
+	IsAdmin      bool         `json:"-"`
+	TokenVersion int64        `json:"-"`
 }
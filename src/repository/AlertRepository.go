@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gocraft/dbr/v2"
+	"github.com/lib/pq"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+)
+
+// ALERT_INBOX_LIMIT is how many of the latest alerts the inbox returns.
+const ALERT_INBOX_LIMIT = 100
+
+type AlertRepository interface {
+	SaveWatch(ctx context.Context, watch model.ProductWatch) (model.ProductWatch, error)
+	UpdateWatch(ctx context.Context, watch model.ProductWatch) (model.ProductWatch, error)
+	DeleteWatch(ctx context.Context, userId, id int64) error
+	ListWatches(ctx context.Context, userId int64) ([]model.ProductWatch, error)
+	ListPurchaseWatches(ctx context.Context, purchaseId, productId int64) ([]model.ProductWatch, error)
+	CreateAlert(ctx context.Context, alert model.PriceAlert) (*model.PriceAlert, error)
+	ListAlerts(ctx context.Context, userId int64, unreadOnly bool) ([]model.PriceAlert, error)
+	MarkAlertRead(ctx context.Context, userId, id int64) (model.PriceAlert, error)
+}
+
+type Alert struct {
+	DbConnection *dbr.Connection
+}
+
+func CreateAlertRepository(connection *dbr.Connection) AlertRepository {
+	return &Alert{
+		DbConnection: connection,
+	}
+}
+
+// SaveWatch creates the watch, or replaces the thresholds when the user already watches the product.
+func (a Alert) SaveWatch(ctx context.Context, watch model.ProductWatch) (model.ProductWatch, error) {
+	statement := a.DbConnection.NewSession(nil).SelectBySql(`
+	INSERT INTO PRODUCT_WATCH(user_id, product_id, price_below, spike_percent)
+		values (?, ?, ?, ?)
+	ON CONFLICT (user_id, product_id) DO UPDATE
+		SET price_below = EXCLUDED.price_below, spike_percent = EXCLUDED.spike_percent, updated_at = NOW()
+	RETURNING *
+	`, watch.UserId, watch.ProductId, watch.PriceBelow, watch.SpikePercent)
+
+	var saved model.ProductWatch
+	err := statement.LoadOneContext(ctx, &saved)
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23503" {
+			return model.ProductWatch{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Product %d not found", *watch.ProductId))
+		}
+		return model.ProductWatch{}, util.MakeErrorUnknown(err)
+	}
+
+	return saved, nil
+}
+
+func (a Alert) UpdateWatch(ctx context.Context, watch model.ProductWatch) (model.ProductWatch, error) {
+	statement := a.DbConnection.NewSession(nil).SelectBySql(`
+	UPDATE PRODUCT_WATCH SET price_below = ?, spike_percent = ?, updated_at = NOW()
+		WHERE id = ? AND user_id = ?
+	RETURNING *
+	`, watch.PriceBelow, watch.SpikePercent, watch.Id, watch.UserId)
+
+	var updated model.ProductWatch
+	err := statement.LoadOneContext(ctx, &updated)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return model.ProductWatch{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Watch %d not found", *watch.Id))
+		}
+		return model.ProductWatch{}, util.MakeErrorUnknown(err)
+	}
+
+	return updated, nil
+}
+
+func (a Alert) DeleteWatch(ctx context.Context, userId, id int64) error {
+	result, err := a.DbConnection.NewSession(nil).DeleteBySql(`
+	DELETE FROM PRODUCT_WATCH WHERE id = ? AND user_id = ?
+	`, id, userId).ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+	if count == 0 {
+		return util.MakeError(util.NOT_FOUND, fmt.Sprintf("Watch %d not found", id))
+	}
+
+	return nil
+}
+
+func (a Alert) ListWatches(ctx context.Context, userId int64) ([]model.ProductWatch, error) {
+	statement := a.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT * FROM PRODUCT_WATCH WHERE user_id = ? ORDER BY created_at DESC
+	`, userId)
+
+	var watches []model.ProductWatch
+	_, err := statement.LoadContext(ctx, &watches)
+	if err != nil {
+		return []model.ProductWatch{}, util.MakeErrorUnknown(err)
+	}
+
+	return watches, nil
+}
+
+// ListPurchaseWatches returns the watches on the product of the participants of the purchase.
+func (a Alert) ListPurchaseWatches(ctx context.Context, purchaseId, productId int64) ([]model.ProductWatch, error) {
+	statement := a.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT w.* FROM PRODUCT_WATCH w
+		INNER JOIN purchase_user pu ON pu.user_id = w.user_id
+	WHERE pu.purchase_id = ? AND w.product_id = ?
+	`, purchaseId, productId)
+
+	var watches []model.ProductWatch
+	_, err := statement.LoadContext(ctx, &watches)
+	if err != nil {
+		return []model.ProductWatch{}, util.MakeErrorUnknown(err)
+	}
+
+	return watches, nil
+}
+
+// CreateAlert stores the alert in the inbox of its user. It returns nil when the same alert was already raised.
+func (a Alert) CreateAlert(ctx context.Context, alert model.PriceAlert) (*model.PriceAlert, error) {
+	statement := a.DbConnection.NewSession(nil).SelectBySql(`
+	INSERT INTO PRICE_ALERT(user_id, watch_id, product_id, market_id, purchase_id, purchase_item_id, type, price, reference_price)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT DO NOTHING
+	RETURNING *
+	`, alert.UserId, alert.WatchId, alert.ProductId, alert.MarketId, alert.PurchaseId, alert.PurchaseItemId,
+		alert.Type, alert.Price, alert.ReferencePrice)
+
+	var created model.PriceAlert
+	err := statement.LoadOneContext(ctx, &created)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, util.MakeErrorUnknown(err)
+	}
+
+	return &created, nil
+}
+
+func (a Alert) ListAlerts(ctx context.Context, userId int64, unreadOnly bool) ([]model.PriceAlert, error) {
+	statement := a.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT * FROM PRICE_ALERT
+	WHERE user_id = ? AND (NOT ? OR read_at IS NULL)
+	ORDER BY created_at DESC, id DESC
+	LIMIT ?
+	`, userId, unreadOnly, ALERT_INBOX_LIMIT)
+
+	var alerts []model.PriceAlert
+	_, err := statement.LoadContext(ctx, &alerts)
+	if err != nil {
+		return []model.PriceAlert{}, util.MakeErrorUnknown(err)
+	}
+
+	return alerts, nil
+}
+
+func (a Alert) MarkAlertRead(ctx context.Context, userId, id int64) (model.PriceAlert, error) {
+	statement := a.DbConnection.NewSession(nil).SelectBySql(`
+	UPDATE PRICE_ALERT SET read_at = COALESCE(read_at, NOW())
+		WHERE id = ? AND user_id = ?
+	RETURNING *
+	`, id, userId)
+
+	var alert model.PriceAlert
+	err := statement.LoadOneContext(ctx, &alert)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return model.PriceAlert{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Alert %d not found", id))
+		}
+		return model.PriceAlert{}, util.MakeErrorUnknown(err)
+	}
+
+	return alert, nil
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gocraft/dbr/v2"
+	"github.com/lib/pq"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+)
+
+// CATEGORY_SUGGESTION_NEIGHBOURS is how many of the most similar categorized products vote on a suggestion.
+const CATEGORY_SUGGESTION_NEIGHBOURS = 10
+
+type CategoryRepository interface {
+	CreateCategory(ctx context.Context, category model.Category) (model.Category, error)
+	UpdateCategory(ctx context.Context, category model.Category) (model.Category, error)
+	DeleteCategory(ctx context.Context, id int64) error
+	GetCategoryById(ctx context.Context, id int64) (model.Category, error)
+	ListCategories(ctx context.Context) ([]model.Category, error)
+	SuggestCategory(ctx context.Context, productName string) (model.Category, error)
+	SuggestCategoriesForPurchase(ctx context.Context, purchaseId int64) (map[int64]int64, error)
+}
+
+type Category struct {
+	DbConnection *dbr.Connection
+}
+
+func CreateCategoryRepository(connection *dbr.Connection) CategoryRepository {
+	return &Category{
+		DbConnection: connection,
+	}
+}
+
+type productCategorySuggestion struct {
+	ProductId  int64 `db:"product_id"`
+	CategoryId int64 `db:"category_id"`
+}
+
+func (c Category) CreateCategory(ctx context.Context, category model.Category) (model.Category, error) {
+	statement := c.DbConnection.NewSession(nil).SelectBySql(`
+	INSERT INTO CATEGORY(id, name, created_at, updated_at)
+		values (default, ?, default, default)
+	RETURNING *
+	`, category.Name)
+
+	_, err := statement.LoadContext(ctx, &category)
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+			return model.Category{}, util.MakeError(util.ALREADY_EXISTS, fmt.Sprintf("Category %s already exists", category.Name))
+		}
+		return model.Category{}, util.MakeErrorUnknown(err)
+	}
+
+	return category, nil
+}
+
+func (c Category) UpdateCategory(ctx context.Context, category model.Category) (model.Category, error) {
+	if category.Id == nil {
+		return model.Category{}, util.MakeError(util.INVALID_INPUT, "invalid Category Id")
+	}
+	statement := c.DbConnection.NewSession(nil).SelectBySql(`
+	UPDATE CATEGORY SET name = ?, updated_at = NOW()
+		WHERE id = ?
+	RETURNING *
+	`, category.Name, category.Id)
+
+	count, err := statement.LoadContext(ctx, &category)
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+			return model.Category{}, util.MakeError(util.ALREADY_EXISTS, fmt.Sprintf("Category %s already exists", category.Name))
+		}
+		return model.Category{}, util.MakeErrorUnknown(err)
+	}
+	if count == 0 {
+		return model.Category{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Category %d not found", *category.Id))
+	}
+
+	return category, nil
+}
+
+func (c Category) DeleteCategory(ctx context.Context, id int64) error {
+	result, err := c.DbConnection.NewSession(nil).DeleteBySql(`
+	DELETE FROM CATEGORY WHERE id = ?
+	`, id).ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+	if count == 0 {
+		return util.MakeError(util.NOT_FOUND, fmt.Sprintf("Category %d not found", id))
+	}
+
+	return nil
+}
+
+func (c Category) GetCategoryById(ctx context.Context, id int64) (model.Category, error) {
+	statement := c.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT * FROM CATEGORY WHERE id = ?
+	`, id)
+
+	var category model.Category
+	err := statement.LoadOneContext(ctx, &category)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return model.Category{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Category %d not found", id))
+		}
+		return model.Category{}, util.MakeErrorUnknown(err)
+	}
+
+	return category, nil
+}
+
+func (c Category) ListCategories(ctx context.Context) ([]model.Category, error) {
+	statement := c.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT * FROM CATEGORY ORDER BY name
+	`)
+
+	var categories []model.Category
+	_, err := statement.LoadContext(ctx, &categories)
+	if err != nil {
+		return []model.Category{}, util.MakeErrorUnknown(err)
+	}
+
+	return categories, nil
+}
+
+// SuggestCategory picks the category of the most similar categorized products, weighting each one by its trigram similarity.
+func (c Category) SuggestCategory(ctx context.Context, productName string) (model.Category, error) {
+	statement := c.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT c.* FROM (
+		SELECT o.category_id, similarity(o.name, ?) simi
+		FROM product o
+		WHERE o.category_id IS NOT NULL AND o.name % ?
+		ORDER BY simi DESC
+		LIMIT ?
+	) s
+		INNER JOIN category c ON c.id = s.category_id
+	GROUP BY c.id
+	ORDER BY SUM(s.simi) DESC
+	LIMIT 1
+	`, productName, productName, CATEGORY_SUGGESTION_NEIGHBOURS)
+
+	var category model.Category
+	err := statement.LoadOneContext(ctx, &category)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return model.Category{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("No category found for %s", productName))
+		}
+		return model.Category{}, util.MakeErrorUnknown(err)
+	}
+
+	return category, nil
+}
+
+// SuggestCategoriesForPurchase suggests a category for every uncategorized product of the purchase, like SuggestCategory.
+// It returns the suggested category id by product id, leaving out the products nothing similar was found for.
+func (c Category) SuggestCategoriesForPurchase(ctx context.Context, purchaseId int64) (map[int64]int64, error) {
+	statement := c.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT u.id product_id, best.category_id category_id
+	FROM (
+		SELECT DISTINCT p.id, p.name
+		FROM purchase_item pi
+			INNER JOIN product p ON p.id = pi.product_id
+		WHERE pi.purchase_id = ? AND p.category_id IS NULL
+	) u
+		INNER JOIN LATERAL (
+			SELECT s.category_id FROM (
+				SELECT o.category_id, similarity(o.name, u.name) simi
+				FROM product o
+				WHERE o.category_id IS NOT NULL AND o.name % u.name
+				ORDER BY simi DESC
+				LIMIT ?
+			) s
+			GROUP BY s.category_id
+			ORDER BY SUM(s.simi) DESC
+			LIMIT 1
+		) best ON TRUE
+	`, purchaseId, CATEGORY_SUGGESTION_NEIGHBOURS)
+
+	var suggestions []productCategorySuggestion
+	_, err := statement.LoadContext(ctx, &suggestions)
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+
+	results := make(map[int64]int64, len(suggestions))
+	for _, suggestion := range suggestions {
+		results[suggestion.ProductId] = suggestion.CategoryId
+	}
+
+	return results, nil
+}
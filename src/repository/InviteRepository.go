@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gocraft/dbr/v2"
+	"github.com/lib/pq"
+	"github.com/ronistone/market-list/src/model"
+	repositoryModel "github.com/ronistone/market-list/src/repository/model"
+	"github.com/ronistone/market-list/src/util"
+)
+
+type InviteRepository interface {
+	CreateInvite(ctx context.Context, purchaseId, inviterId int64, inviteeId *int64, email string, role model.PurchaseRole) (model.Invite, error)
+	GetInviteById(ctx context.Context, id int64) (model.Invite, error)
+	ListPendingInvites(ctx context.Context, userId int64) ([]model.Invite, error)
+	AcceptInvite(ctx context.Context, id, userId int64) (int64, error)
+	DeclineInvite(ctx context.Context, id, userId int64) error
+}
+
+type Invite struct {
+	DbConnection *dbr.Connection
+}
+
+const (
+	FETCH_INVITE = `SELECT
+		i.id invite_id,
+		i.email invite_email,
+		i.status invite_status,
+		i.role invite_role,
+		i.created_at invite_created_at,
+		i.updated_at invite_updated_at,
+		p.id purchase_id,
+		p.name purchase_name,
+		u.id inviter_id,
+		u.name inviter_name,
+		u.email inviter_email
+	FROM purchase_invite i
+		INNER JOIN purchase p ON p.id = i.purchase_id
+		INNER JOIN market_user u ON u.id = i.inviter_id
+	WHERE 1=1
+`
+)
+
+func CreateInviteRepository(connection *dbr.Connection) InviteRepository {
+	return &Invite{
+		DbConnection: connection,
+	}
+}
+
+// CreateInvite invites the user with the id inviteeId. Email is the address they had when invited, invites are matched
+// by the user id so that changing the email of an account does not give access to invites sent to someone else.
+// Without an inviteeId the address has no account yet, and the invite is bound to whoever signs up with it.
+func (i Invite) CreateInvite(ctx context.Context, purchaseId, inviterId int64, inviteeId *int64, email string, role model.PurchaseRole) (model.Invite, error) {
+	statement := i.DbConnection.NewSession(nil).SelectBySql(`
+	INSERT INTO purchase_invite(purchase_id, inviter_id, invitee_id, email, role)
+		VALUES (?, ?, ?, ?, ?)
+	RETURNING id
+	`, purchaseId, inviterId, inviteeId, email, role)
+
+	var id int64
+	err := statement.LoadOneContext(ctx, &id)
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+			return model.Invite{}, util.MakeError(util.ALREADY_EXISTS, fmt.Sprintf("%s already has a pending invite", email))
+		}
+		return model.Invite{}, util.MakeErrorUnknown(err)
+	}
+
+	return i.GetInviteById(ctx, id)
+}
+
+func (i Invite) GetInviteById(ctx context.Context, id int64) (model.Invite, error) {
+	statement := i.DbConnection.NewSession(nil).SelectBySql(FETCH_INVITE+`
+	AND i.id = ?
+	`, id)
+
+	var invite repositoryModel.InviteEntity
+	err := statement.LoadOneContext(ctx, &invite)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return model.Invite{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Invite %d not found", id))
+		}
+		return model.Invite{}, util.MakeErrorUnknown(err)
+	}
+
+	return invite.ToInvite(), nil
+}
+
+func (i Invite) ListPendingInvites(ctx context.Context, userId int64) ([]model.Invite, error) {
+	statement := i.DbConnection.NewSession(nil).SelectBySql(FETCH_INVITE+`
+	AND i.invitee_id = ? AND i.status = ?
+	ORDER BY i.created_at DESC
+	`, userId, model.INVITE_PENDING)
+
+	var invites []repositoryModel.InviteEntity
+	_, err := statement.LoadContext(ctx, &invites)
+	if err != nil {
+		return []model.Invite{}, util.MakeErrorUnknown(err)
+	}
+
+	results := make([]model.Invite, len(invites))
+	for j, v := range invites {
+		results[j] = v.ToInvite()
+	}
+
+	return results, nil
+}
+
+func (i Invite) AcceptInvite(ctx context.Context, id, userId int64) (int64, error) {
+	session := i.DbConnection.NewSession(nil)
+	tx, err := session.Begin()
+	if err != nil {
+		return 0, util.MakeErrorUnknown(err)
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var accepted repositoryModel.InviteEntity
+	err = tx.SelectBySql(`
+	UPDATE purchase_invite SET status = ?, updated_at = NOW()
+		WHERE id = ? AND invitee_id = ? AND status = ?
+	RETURNING purchase_id, role invite_role
+	`, model.INVITE_ACCEPTED, id, userId, model.INVITE_PENDING).LoadOneContext(ctx, &accepted)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return 0, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Invite %d not found", id))
+		}
+		return 0, util.MakeErrorUnknown(err)
+	}
+
+	purchaseId := *accepted.PurchaseId
+	_, err = tx.InsertBySql(`
+	INSERT INTO purchase_user (purchase_id, user_id, role) VALUES (?, ?, ?)
+	ON CONFLICT DO NOTHING
+	`, purchaseId, userId, accepted.Role).ExecContext(ctx)
+	if err != nil {
+		return 0, util.MakeErrorUnknown(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, util.MakeErrorUnknown(err)
+	}
+
+	return purchaseId, nil
+}
+
+func (i Invite) DeclineInvite(ctx context.Context, id, userId int64) error {
+	statement := i.DbConnection.NewSession(nil).UpdateBySql(`
+	UPDATE purchase_invite SET status = ?, updated_at = NOW()
+		WHERE id = ? AND invitee_id = ? AND status = ?
+	`, model.INVITE_DECLINED, id, userId, model.INVITE_PENDING)
+
+	result, err := statement.ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return util.MakeError(util.NOT_FOUND, fmt.Sprintf("Invite %d not found", id))
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"github.com/gocraft/dbr/v2"
+	"github.com/lib/pq"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+)
+
+// MARKET_CHECK_ORDER_PURCHASES is how many of the latest purchases at a market are used to learn its walking order.
+const MARKET_CHECK_ORDER_PURCHASES = 20
+
+// MARKET_CHECKS ranks the items checked off in the latest purchases at a market by the moment they were checked off,
+// from 0 for the first to 1 for the last of each purchase. Purchases with a single checked item say nothing about order.
+const MARKET_CHECKS = `WITH recent AS (
+		SELECT p.id FROM purchase p
+		WHERE p.market_id = ?
+		  AND (SELECT COUNT(*) FROM purchase_item c WHERE c.purchase_id = p.id AND c.purchased) > 1
+		ORDER BY p.created_at DESC
+		LIMIT ?
+	), checks AS (
+		SELECT pi.product_id,
+			PERCENT_RANK() OVER (PARTITION BY pi.purchase_id ORDER BY pi.purchased_updated_at) position
+		FROM purchase_item pi
+		WHERE pi.purchase_id IN (SELECT id FROM recent)
+		  AND pi.purchased AND pi.purchased_updated_at IS NOT NULL
+	)
+	`
+
+type MarketLayoutRepository interface {
+	GetMarketLayout(ctx context.Context, marketId int64) (model.MarketLayout, error)
+	SaveMarketLayout(ctx context.Context, layout model.MarketLayout) error
+	GetMarketCheckOrder(ctx context.Context, marketId int64) (model.MarketCheckOrder, error)
+}
+
+type MarketLayout struct {
+	DbConnection *dbr.Connection
+}
+
+func CreateMarketLayoutRepository(connection *dbr.Connection) MarketLayoutRepository {
+	return &MarketLayout{
+		DbConnection: connection,
+	}
+}
+
+type marketAisleMapping struct {
+	AisleId int64 `db:"aisle_id"`
+	Id      int64 `db:"id"`
+}
+
+type marketCheckPosition struct {
+	Id       int64   `db:"id"`
+	Position float64 `db:"position"`
+}
+
+func (m MarketLayout) GetMarketLayout(ctx context.Context, marketId int64) (model.MarketLayout, error) {
+	session := m.DbConnection.NewSession(nil)
+	layout := model.MarketLayout{MarketId: marketId, Aisles: []model.MarketAisle{}}
+
+	_, err := session.SelectBySql(`
+	SELECT id, name, position FROM MARKET_AISLE
+	WHERE market_id = ?
+	ORDER BY position
+	`, marketId).LoadContext(ctx, &layout.Aisles)
+	if err != nil {
+		return model.MarketLayout{}, util.MakeErrorUnknown(err)
+	}
+
+	var categories []marketAisleMapping
+	_, err = session.SelectBySql(`
+	SELECT aisle_id, category_id id FROM MARKET_AISLE_CATEGORY
+	WHERE market_id = ?
+	ORDER BY category_id
+	`, marketId).LoadContext(ctx, &categories)
+	if err != nil {
+		return model.MarketLayout{}, util.MakeErrorUnknown(err)
+	}
+
+	var products []marketAisleMapping
+	_, err = session.SelectBySql(`
+	SELECT aisle_id, product_id id FROM MARKET_AISLE_PRODUCT
+	WHERE market_id = ?
+	ORDER BY product_id
+	`, marketId).LoadContext(ctx, &products)
+	if err != nil {
+		return model.MarketLayout{}, util.MakeErrorUnknown(err)
+	}
+
+	aisles := make(map[int64]*model.MarketAisle, len(layout.Aisles))
+	for i := range layout.Aisles {
+		layout.Aisles[i].CategoryIds = []int64{}
+		layout.Aisles[i].ProductIds = []int64{}
+		aisles[*layout.Aisles[i].Id] = &layout.Aisles[i]
+	}
+	for _, category := range categories {
+		aisle := aisles[category.AisleId]
+		aisle.CategoryIds = append(aisle.CategoryIds, category.Id)
+	}
+	for _, product := range products {
+		aisle := aisles[product.AisleId]
+		aisle.ProductIds = append(aisle.ProductIds, product.Id)
+	}
+
+	return layout, nil
+}
+
+// SaveMarketLayout replaces the aisles of the market and their mappings. Learned check-off order is kept.
+func (m MarketLayout) SaveMarketLayout(ctx context.Context, layout model.MarketLayout) error {
+	tx, err := m.DbConnection.NewSession(nil).Begin()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	_, err = tx.DeleteBySql(`
+	DELETE FROM MARKET_AISLE WHERE market_id = ?
+	`, layout.MarketId).ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	for _, aisle := range layout.Aisles {
+		var aisleId int64
+		err = tx.SelectBySql(`
+		INSERT INTO MARKET_AISLE(market_id, name, position)
+			values (?, ?, ?)
+		RETURNING id
+		`, layout.MarketId, aisle.Name, aisle.Position).LoadOneContext(ctx, &aisleId)
+		if err != nil {
+			return layoutError(err)
+		}
+
+		for _, categoryId := range aisle.CategoryIds {
+			_, err = tx.InsertBySql(`
+			INSERT INTO MARKET_AISLE_CATEGORY(market_id, category_id, aisle_id)
+				values (?, ?, ?)
+			`, layout.MarketId, categoryId, aisleId).ExecContext(ctx)
+			if err != nil {
+				return layoutError(err)
+			}
+		}
+
+		for _, productId := range aisle.ProductIds {
+			_, err = tx.InsertBySql(`
+			INSERT INTO MARKET_AISLE_PRODUCT(market_id, product_id, aisle_id)
+				values (?, ?, ?)
+			`, layout.MarketId, productId, aisleId).ExecContext(ctx)
+			if err != nil {
+				return layoutError(err)
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
+
+func layoutError(err error) error {
+	if pqError, ok := err.(*pq.Error); ok && (pqError.Code == "23503" || pqError.Code == "23505") {
+		return util.MakeError(util.INVALID_INPUT, pqError.Detail)
+	}
+	return util.MakeErrorUnknown(err)
+}
+
+// GetMarketCheckOrder learns the walking order of the market from the latest purchases there. Checked items are
+// placed in the aisle of their product, or else of its category, to also learn where each aisle is walked by.
+func (m MarketLayout) GetMarketCheckOrder(ctx context.Context, marketId int64) (model.MarketCheckOrder, error) {
+	session := m.DbConnection.NewSession(nil)
+
+	var products []marketCheckPosition
+	_, err := session.SelectBySql(MARKET_CHECKS+`
+	SELECT product_id id, AVG(position) position
+	FROM checks
+	GROUP BY product_id
+	`, marketId, MARKET_CHECK_ORDER_PURCHASES).LoadContext(ctx, &products)
+	if err != nil {
+		return model.MarketCheckOrder{}, util.MakeErrorUnknown(err)
+	}
+
+	var aisles []marketCheckPosition
+	_, err = session.SelectBySql(MARKET_CHECKS+`
+	SELECT COALESCE(ap.aisle_id, ac.aisle_id) id, AVG(checks.position) position
+	FROM checks
+		INNER JOIN product p ON p.id = checks.product_id
+		LEFT JOIN market_aisle_product ap ON ap.market_id = ? AND ap.product_id = p.id
+		LEFT JOIN market_aisle_category ac ON ac.market_id = ? AND ac.category_id = p.category_id
+	WHERE COALESCE(ap.aisle_id, ac.aisle_id) IS NOT NULL
+	GROUP BY COALESCE(ap.aisle_id, ac.aisle_id)
+	`, marketId, MARKET_CHECK_ORDER_PURCHASES, marketId, marketId).LoadContext(ctx, &aisles)
+	if err != nil {
+		return model.MarketCheckOrder{}, util.MakeErrorUnknown(err)
+	}
+
+	checkOrder := model.MarketCheckOrder{
+		Products: make(map[int64]float64, len(products)),
+		Aisles:   make(map[int64]float64, len(aisles)),
+	}
+	for _, product := range products {
+		checkOrder.Products[product.Id] = product.Position
+	}
+	for _, aisle := range aisles {
+		checkOrder.Aisles[aisle.Id] = aisle.Position
+	}
+
+	return checkOrder, nil
+}
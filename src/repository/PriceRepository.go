@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"github.com/gocraft/dbr/v2"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+	"strings"
+	"time"
+)
+
+// FETCH_PRICE_OBSERVATION lists the prices the user paid, taken from the checked items of their purchases at a market.
+const FETCH_PRICE_OBSERVATION = `SELECT * FROM (
+	SELECT pi.product_id,
+	       p.market_id,
+	       m.name market_name,
+	       pi.purchase_id,
+	       pi.id purchase_item_id,
+	       pi.price,
+	       pi.quantity,
+	       COALESCE(pi.purchased_updated_at, p.created_at) observed_at
+	FROM purchase_item pi
+		INNER JOIN purchase p ON p.id = pi.purchase_id
+		INNER JOIN purchase_user pu ON pu.purchase_id = p.id AND pu.user_id = ?
+		INNER JOIN market m ON m.id = p.market_id
+	WHERE pi.purchased AND pi.price IS NOT NULL
+	) o
+	WHERE TRUE
+`
+
+type PriceRepository interface {
+	ListProductPrices(ctx context.Context, userId, productId int64, filter model.PriceFilter) ([]model.PriceObservation, error)
+	ListLatestPrices(ctx context.Context, userId int64, productIds []int64) ([]model.PriceObservation, error)
+	GetAveragePrice(ctx context.Context, userId, productId int64, marketId *int64, since time.Time, excludeItemId int64) (*float64, error)
+}
+
+type averagePrice struct {
+	Average float64 `db:"average"`
+	Samples int64   `db:"samples"`
+}
+
+type Price struct {
+	DbConnection *dbr.Connection
+}
+
+func CreatePriceRepository(connection *dbr.Connection) PriceRepository {
+	return &Price{
+		DbConnection: connection,
+	}
+}
+
+// ListProductPrices returns the observed prices of the product ordered by market and then by time.
+func (p Price) ListProductPrices(ctx context.Context, userId, productId int64, filter model.PriceFilter) ([]model.PriceObservation, error) {
+	query := strings.Builder{}
+	query.WriteString(FETCH_PRICE_OBSERVATION)
+	query.WriteString("	AND o.product_id = ?\n")
+	args := []interface{}{userId, productId}
+
+	if filter.From != nil {
+		query.WriteString("	AND o.observed_at >= ?\n")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		query.WriteString("	AND o.observed_at < ?\n")
+		args = append(args, *filter.To)
+	}
+	query.WriteString("	ORDER BY o.market_name, o.market_id, o.observed_at, o.purchase_id")
+
+	var observations []model.PriceObservation
+	_, err := p.DbConnection.NewSession(nil).SelectBySql(query.String(), args...).LoadContext(ctx, &observations)
+	if err != nil {
+		return []model.PriceObservation{}, util.MakeErrorUnknown(err)
+	}
+
+	return observations, nil
+}
+
+// ListLatestPrices returns the last price observed for each of the products at each market.
+func (p Price) ListLatestPrices(ctx context.Context, userId int64, productIds []int64) ([]model.PriceObservation, error) {
+	if len(productIds) == 0 {
+		return []model.PriceObservation{}, nil
+	}
+
+	statement := p.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT DISTINCT ON (o.product_id, o.market_id) * FROM (
+	`+FETCH_PRICE_OBSERVATION+`
+		AND o.product_id IN ?
+	) o
+	ORDER BY o.product_id, o.market_id, o.observed_at DESC, o.purchase_id DESC
+	`, userId, productIds)
+
+	var observations []model.PriceObservation
+	_, err := statement.LoadContext(ctx, &observations)
+	if err != nil {
+		return []model.PriceObservation{}, util.MakeErrorUnknown(err)
+	}
+
+	return observations, nil
+}
+
+// GetAveragePrice returns the average price the user paid for the product since the given time, only at the market
+// when one is given and leaving out one item. It returns nil when there is no such price.
+func (p Price) GetAveragePrice(ctx context.Context, userId, productId int64, marketId *int64, since time.Time, excludeItemId int64) (*float64, error) {
+	statement := p.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT COALESCE(AVG(a.price), 0) average, COUNT(*) samples FROM (
+	`+FETCH_PRICE_OBSERVATION+`
+		AND o.product_id = ?
+		AND o.observed_at >= ?
+		AND o.purchase_item_id <> ?
+		AND (?::BIGINT IS NULL OR o.market_id = ?)
+	) a
+	`, userId, productId, since, excludeItemId, marketId, marketId)
+
+	var average averagePrice
+	err := statement.LoadOneContext(ctx, &average)
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+	if average.Samples == 0 {
+		return nil, nil
+	}
+
+	return &average.Average, nil
+}
@@ -12,7 +12,7 @@ import (
 
 type ProductRepository interface {
 	CreateProduct(ctx context.Context, product model.Product) (model.Product, error)
-	UpdateProduct(ctx context.Context, product model.Product) (model.Product, error)
+	UpdateProduct(ctx context.Context, product model.Product, expectedVersion *int64) (model.Product, error)
 	GetProductByEan(ctx context.Context, ean string) (model.Product, error)
 	GetProductById(ctx context.Context, id int64) (model.Product, error)
 	GetProductByName(ctx context.Context, name string, limit int) ([]model.Product, error)
@@ -30,40 +30,90 @@ func CreateProductRepository(connection *dbr.Connection) ProductRepository {
 
 func (p Product) CreateProduct(ctx context.Context, product model.Product) (model.Product, error) {
 	statement := p.DbConnection.NewSession(nil).SelectBySql(`
-	INSERT INTO PRODUCT(id, ean, name, unit, size, created_at, updated_at) 
-		values (default, ?, ?, ?, ?, default, default)
+	INSERT INTO PRODUCT(id, ean, name, unit, size, category_id, created_at, updated_at) 
+		values (default, ?, ?, ?, ?, ?, default, default)
 	RETURNING *
-	`, product.Ean, product.Name, product.Unit, product.Size)
+	`, product.Ean, product.Name, product.Unit, product.Size, product.CategoryId)
 
 	_, err := statement.LoadContext(ctx, &product)
 	if err != nil {
-		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
-			return model.Product{}, util.MakeError(util.ALREADY_EXISTS, pqError.Message)
-		}
-		return model.Product{}, util.MakeErrorUnknown(err)
+		return model.Product{}, productWriteError(err, product)
 	}
 
 	return product, nil
 }
 
-func (p Product) UpdateProduct(ctx context.Context, product model.Product) (model.Product, error) {
+// UpdateProduct overwrites the product. When expectedVersion is set the update only happens
+// if the product is still at that version, failing with PRECONDITION_FAILED otherwise.
+func (p Product) UpdateProduct(ctx context.Context, product model.Product, expectedVersion *int64) (model.Product, error) {
+	return updateProduct(ctx, p.DbConnection.NewSession(nil), product, expectedVersion)
+}
+
+func updateProduct(ctx context.Context, runner dbr.SessionRunner, product model.Product, expectedVersion *int64) (model.Product, error) {
 	if product.Id == nil {
 		return model.Product{}, util.MakeError(util.INVALID_INPUT, "invalid Product Id")
 	}
-	statement := p.DbConnection.NewSession(nil).SelectBySql(`
-	UPDATE PRODUCT SET ean = ?, name = ?, unit = ?, size = ?, updated_at = NOW() 
-		WHERE id = ?
+	statement := runner.SelectBySql(`
+	UPDATE PRODUCT SET ean = ?, name = ?, unit = ?, size = ?, category_id = ?, updated_at = NOW() 
+		WHERE id = ? AND (?::BIGINT IS NULL OR version = ?)
+	RETURNING *
+	`, product.Ean, product.Name, product.Unit, product.Size, product.CategoryId, product.Id, expectedVersion, expectedVersion)
+
+	count, err := statement.LoadContext(ctx, &product)
+	if err != nil {
+		return model.Product{}, productWriteError(err, product)
+	}
+
+	if count == 0 && expectedVersion != nil {
+		var exists bool
+		err = runner.SelectBySql(`
+		SELECT EXISTS (SELECT 1 FROM product WHERE id = ?)
+		`, product.Id).LoadOneContext(ctx, &exists)
+		if err != nil {
+			return model.Product{}, util.MakeErrorUnknown(err)
+		}
+		if !exists {
+			return model.Product{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Product %d not found", *product.Id))
+		}
+		return model.Product{}, util.MakeError(util.PRECONDITION_FAILED, fmt.Sprintf("Product %d was modified, expected version %d", *product.Id, *expectedVersion))
+	}
+
+	return product, nil
+}
+
+// saveItemProduct writes the product of a purchase item inside tx. A product with an id is updated at its Version. A
+// product without one is created, unless a product with the same EAN exists, which an earlier operation of the same
+// transaction may have created: that one is returned unchanged, since the item did not know its version.
+func saveItemProduct(ctx context.Context, tx *dbr.Tx, product model.Product) (model.Product, error) {
+	if product.Id != nil {
+		return updateProduct(ctx, tx, product, &product.Version)
+	}
+
+	statement := tx.SelectBySql(`
+	INSERT INTO PRODUCT(ean, name, unit, size, category_id)
+		VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (ean) DO UPDATE SET ean = EXCLUDED.ean
 	RETURNING *
-	`, product.Ean, product.Name, product.Unit, product.Size, product.Id)
+	`, product.Ean, product.Name, product.Unit, product.Size, product.CategoryId)
 
 	_, err := statement.LoadContext(ctx, &product)
 	if err != nil {
-		return model.Product{}, util.MakeErrorUnknown(err)
+		return model.Product{}, productWriteError(err, product)
 	}
 
 	return product, nil
 }
 
+func productWriteError(err error, product model.Product) error {
+	if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+		return util.MakeError(util.ALREADY_EXISTS, pqError.Message)
+	}
+	if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23503" {
+		return util.MakeError(util.INVALID_INPUT, fmt.Sprintf("Category %d not found", *product.CategoryId))
+	}
+	return util.MakeErrorUnknown(err)
+}
+
 func (p Product) GetProductByEan(ctx context.Context, ean string) (model.Product, error) {
 	statement := p.DbConnection.NewSession(nil).SelectBySql(`
 	SELECT * FROM product where ean = ?
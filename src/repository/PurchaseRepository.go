@@ -2,24 +2,39 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/gocraft/dbr/v2"
+	"github.com/lib/pq"
 	"github.com/ronistone/market-list/src/model"
 	repositoryModel "github.com/ronistone/market-list/src/repository/model"
 	"github.com/ronistone/market-list/src/util"
+	"strings"
 )
 
 type PurchaseRepository interface {
 	CreatePurchase(ctx context.Context, purchase model.Purchase) (model.Purchase, error)
+	ClonePurchase(ctx context.Context, userId, sourceId int64, purchase model.Purchase, options model.PurchaseCloneOptions) (model.Purchase, error)
+	UpdatePurchase(ctx context.Context, purchase model.Purchase) error
 	DeletePurchase(ctx context.Context, userId, id int64) error
-	AddPurchaseItem(ctx context.Context, userId, purchaseId int64, item model.PurchaseItem) (model.Purchase, error)
+	RemovePurchaseUser(ctx context.Context, purchaseId, userId int64) error
+	GetPurchaseRole(ctx context.Context, userId, purchaseId int64) (model.PurchaseRole, error)
+	UpdatePurchaseUserRole(ctx context.Context, purchaseId, userId int64, role model.PurchaseRole) error
+	AddPurchaseItem(ctx context.Context, userId, purchaseId int64, item model.PurchaseItem) (model.PurchaseItem, error)
+	FindMergeablePurchaseItem(ctx context.Context, userId, purchaseId int64, product model.Product) (*model.PurchaseItem, error)
+	MergePurchaseItem(ctx context.Context, userId, purchaseId, itemId int64, item model.PurchaseItem) (model.PurchaseItem, error)
+	ConsolidatePurchaseItems(ctx context.Context, purchaseId int64) ([]int64, []int64, error)
 	RemovePurchaseItem(ctx context.Context, userId, purchaseId int64, itemId int64) (model.Purchase, error)
-	UpdatePurchaseItem(ctx context.Context, userId, purchaseId, itemId int64, item model.PurchaseItem) error
+	ApplyPurchaseItemOperations(ctx context.Context, purchaseId int64, operations []model.PurchaseItemOperation, atomic bool) ([]model.PurchaseItemOperationResult, error)
+	UpdatePurchaseItem(ctx context.Context, userId, purchaseId, itemId int64, item model.PurchaseItem, expectedVersion *int64) error
+	MovePurchaseItem(ctx context.Context, purchaseId, itemId int64, move model.PurchaseItemMove) error
 	GetPurchaseById(ctx context.Context, userId, id int64) (model.Purchase, error)
-	GetPurchaseByIdFetchItems(ctx context.Context, userId, id int64) (model.Purchase, error)
+	GetPurchaseByIdFetchItems(ctx context.Context, userId, id int64, sort model.PurchaseItemSort) (model.Purchase, error)
 	GetPurchaseItemById(ctx context.Context, userId, purchaseId int64, id int64) (model.PurchaseItem, error)
-	ListPurchase(ctx context.Context, userId int64) ([]model.Purchase, error)
+	ListPurchase(ctx context.Context, userId int64, filter model.PurchaseFilter) (model.PurchasePage, error)
+	ListPurchasesByIds(ctx context.Context, userId int64, ids []int64) ([]model.Purchase, error)
+	ListPurchaseItems(ctx context.Context, userId int64, purchaseIds, itemIds []int64) ([]model.PurchaseItem, error)
 }
 
 type Purchase struct {
@@ -28,17 +43,25 @@ type Purchase struct {
 
 const (
 	FETCH_PURCHASE_ITEM = `SELECT pi.id purchase_item_id,
+       pi.purchase_id purchase_item_purchase_id,
        pi.purchased purchase_item_purchased,
        pi.quantity purchase_item_quantity,
        pi.created_at purchase_item_created_at,
        pi.price purchase_item_price,
+       pi.version purchase_item_version,
+       pi.notes purchase_item_notes,
+       pi.priority purchase_item_priority,
+       pi.optional purchase_item_optional,
+       pi.position purchase_item_position,
        p.id prod_id,
        p.name prod_name,
        p.ean prod_ean,
        p.unit prod_unit,
        p.size prod_size,
        p.created_at prod_created_at,
-       p.updated_at prod_updated_at
+       p.updated_at prod_updated_at,
+       p.version prod_version,
+       p.category_id prod_category_id
 FROM purchase_item pi 
     INNER JOIN product p ON p.id = pi.product_id
 	INNER JOIN purchase_user pu ON pu.purchase_id = pi.purchase_id AND pu.user_id = ?
@@ -49,13 +72,26 @@ FROM purchase_item pi
 		p.created_at purchase_created_at,
 		p.name purchase_name,
 		p.is_favorite purchase_is_favorite,
+		pu.role purchase_role,
 		m.id _market_id,
 		m.name market_name,
 		m.created_at market_created_at,
-		m.updated_at market_updated_at
+		m.updated_at market_updated_at,
+		totals.total_expected purchase_total_expected,
+		totals.total_spent purchase_total_spent,
+		totals.item_count purchase_item_count,
+		totals.purchased_item_count purchase_purchased_item_count
 	FROM purchase p
 	    INNER JOIN purchase_user pu on pu.user_id = ? AND pu.purchase_id = p.id
 	    LEFT JOIN market m ON p.market_id = m.id
+	    LEFT JOIN LATERAL (
+	        SELECT COALESCE(SUM(pi.price * pi.quantity), 0) total_expected,
+	            COALESCE(SUM(pi.price * pi.quantity) FILTER (WHERE pi.purchased), 0) total_spent,
+	            COUNT(pi.id) item_count,
+	            COUNT(pi.id) FILTER (WHERE pi.purchased) purchased_item_count
+	        FROM purchase_item pi
+	        WHERE pi.purchase_id = p.id
+	    ) totals ON TRUE
 	WHERE 1=1
 `
 )
@@ -68,9 +104,13 @@ func CreatePurchaseRepository(connection *dbr.Connection) PurchaseRepository {
 
 func relateUsersToPurchase(ctx context.Context, tx *dbr.Tx, purchaseId int64, users []model.User) error {
 	for _, user := range users {
+		role := user.Role
+		if !role.IsValid() {
+			role = model.PURCHASE_ROLE_EDITOR
+		}
 		statement := tx.InsertBySql(`
-			INSERT INTO PURCHASE_USER (purchase_id, user_id) VALUES (?, ?)
-		`, purchaseId, user.Id)
+			INSERT INTO PURCHASE_USER (purchase_id, user_id, role) VALUES (?, ?, ?)
+		`, purchaseId, user.Id, role)
 		_, err := statement.ExecContext(ctx)
 		if err != nil {
 			return err
@@ -113,6 +153,101 @@ func (p Purchase) CreatePurchase(ctx context.Context, purchase model.Purchase) (
 	return purchase, nil
 }
 
+// ClonePurchase copies the source purchase into a new one owned by the user. With KeepParticipants the other
+// participants of the source are invited to the clone, as editors when they owned the source.
+func (p Purchase) ClonePurchase(ctx context.Context, userId, sourceId int64, purchase model.Purchase, options model.PurchaseCloneOptions) (model.Purchase, error) {
+	session := p.DbConnection.NewSession(nil)
+	tx, err := session.Begin()
+	if err != nil {
+		return model.Purchase{}, util.MakeErrorUnknown(err)
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	err = tx.InsertBySql(`
+	INSERT INTO PURCHASE(CREATED_AT, NAME, MARKET_ID, IS_FAVORITE) 
+		values (default, ?, ?, ?)
+	RETURNING *
+	`, purchase.Name, purchase.MarketId, purchase.IsFavorite).LoadContext(ctx, &purchase)
+	if err != nil {
+		return model.Purchase{}, util.MakeErrorUnknown(err)
+	}
+
+	err = relateUsersToPurchase(ctx, tx, *purchase.Id, purchase.Users)
+	if err != nil {
+		return model.Purchase{}, util.MakeErrorUnknown(err)
+	}
+
+	_, err = tx.InsertBySql(`
+	INSERT INTO PURCHASE_ITEM(PURCHASE_ID, PRODUCT_ID, QUANTITY, PRICE, PURCHASED, NOTES, PRIORITY, OPTIONAL, POSITION)
+	SELECT ?, pi.product_id, pi.quantity, CASE WHEN ? THEN pi.price END, FALSE, pi.notes, pi.priority, pi.optional, pi.position
+	FROM purchase_item pi
+	WHERE pi.purchase_id = ?
+	ORDER BY pi.id
+	`, *purchase.Id, options.KeepPrices, sourceId).ExecContext(ctx)
+	if err != nil {
+		return model.Purchase{}, util.MakeErrorUnknown(err)
+	}
+
+	if options.KeepTags {
+		_, err = tx.InsertBySql(`
+		INSERT INTO TAG_PURCHASE(PURCHASE_ID, TAG_ID)
+		SELECT ?, tp.tag_id
+		FROM tag_purchase tp
+			INNER JOIN tag t ON t.id = tp.tag_id
+			INNER JOIN purchase_user pu ON pu.user_id = t.user_id AND pu.purchase_id = ?
+		WHERE tp.purchase_id = ?
+		`, *purchase.Id, *purchase.Id, sourceId).ExecContext(ctx)
+		if err != nil {
+			return model.Purchase{}, util.MakeErrorUnknown(err)
+		}
+	}
+
+	if options.KeepParticipants {
+		_, err = tx.InsertBySql(`
+		INSERT INTO PURCHASE_INVITE(PURCHASE_ID, INVITER_ID, INVITEE_ID, EMAIL, ROLE)
+		SELECT ?, ?, mu.id, mu.email, CASE WHEN pu.role = ? THEN ? ELSE pu.role END
+		FROM purchase_user pu
+			INNER JOIN market_user mu ON mu.id = pu.user_id
+		WHERE pu.purchase_id = ? AND pu.user_id <> ?
+		`, *purchase.Id, userId, model.PURCHASE_ROLE_OWNER, model.PURCHASE_ROLE_EDITOR, sourceId, userId).ExecContext(ctx)
+		if err != nil {
+			return model.Purchase{}, util.MakeErrorUnknown(err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return model.Purchase{}, util.MakeErrorUnknown(err)
+	}
+
+	return purchase, nil
+}
+
+func (p Purchase) UpdatePurchase(ctx context.Context, purchase model.Purchase) error {
+	statement := p.DbConnection.NewSession(nil).UpdateBySql(`
+	UPDATE purchase SET name = ?, market_id = ?, is_favorite = ?
+		WHERE id = ?
+	`, purchase.Name, purchase.MarketId, purchase.IsFavorite, purchase.Id)
+
+	result, err := statement.ExecContext(ctx)
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23503" {
+			return util.MakeError(util.INVALID_INPUT, fmt.Sprintf("Market %d not found", *purchase.MarketId))
+		}
+		return util.MakeErrorUnknown(err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+	if count == 0 {
+		return util.MakeError(util.NOT_FOUND, fmt.Sprintf("Purchase %d not found", *purchase.Id))
+	}
+
+	return nil
+}
+
 func (p Purchase) DeletePurchase(ctx context.Context, userId, id int64) error {
 	statement := p.DbConnection.NewSession(nil).DeleteBySql(`
 	DELETE FROM purchase where id = (
@@ -128,33 +263,313 @@ func (p Purchase) DeletePurchase(ctx context.Context, userId, id int64) error {
 	return nil
 }
 
-func (p Purchase) UpdatePurchaseItem(ctx context.Context, userId, purchaseId int64, itemId int64, item model.PurchaseItem) error {
+func (p Purchase) RemovePurchaseUser(ctx context.Context, purchaseId, userId int64) error {
 	statement := p.DbConnection.NewSession(nil).DeleteBySql(`
+	DELETE FROM purchase_user WHERE purchase_id = ? AND user_id = ?
+	`, purchaseId, userId)
+
+	_, err := statement.ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
+
+func (p Purchase) GetPurchaseRole(ctx context.Context, userId, purchaseId int64) (model.PurchaseRole, error) {
+	statement := p.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT role FROM purchase_user WHERE user_id = ? AND purchase_id = ?
+	`, userId, purchaseId)
+
+	var role string
+	err := statement.LoadOneContext(ctx, &role)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return "", util.MakeError(util.NOT_FOUND, fmt.Sprintf("Purchase %d not found", purchaseId))
+		}
+		return "", util.MakeErrorUnknown(err)
+	}
+
+	return model.PurchaseRole(role), nil
+}
+
+func (p Purchase) UpdatePurchaseUserRole(ctx context.Context, purchaseId, userId int64, role model.PurchaseRole) error {
+	statement := p.DbConnection.NewSession(nil).UpdateBySql(`
+	UPDATE purchase_user SET role = ? WHERE purchase_id = ? AND user_id = ?
+	`, role, purchaseId, userId)
+
+	_, err := statement.ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
+
+// UpdatePurchaseItem overwrites the item. When expectedVersion is set the update only happens
+// if the item is still at that version, failing with PRECONDITION_FAILED otherwise.
+func (p Purchase) UpdatePurchaseItem(ctx context.Context, userId, purchaseId int64, itemId int64, item model.PurchaseItem, expectedVersion *int64) error {
+	statement := p.DbConnection.NewSession(nil).UpdateBySql(`
 	UPDATE PURCHASE_ITEM pi
-		SET purchased = ?, quantity = ?, price = ?, product_id = ?
+		SET purchased = ?, quantity = ?, price = ?, product_id = ?,
+			notes = NULLIF(COALESCE(?, pi.notes), ''), priority = COALESCE(?, pi.priority), optional = COALESCE(?, pi.optional)
 		FROM purchase_user pu
 		WHERE pi.id = ? AND pi.purchase_id = pu.purchase_id AND pu.user_id = ? AND pi.purchase_id = ?
-	`, item.Purchased, item.Quantity, item.Price, item.Product.Id, itemId, userId, purchaseId)
+			AND (?::BIGINT IS NULL OR pi.version = ?)
+	`, item.Purchased, item.Quantity, item.Price, item.Product.Id, item.Notes, item.Priority, item.Optional,
+		itemId, userId, purchaseId, expectedVersion, expectedVersion)
 
-	_, err := statement.ExecContext(ctx)
+	result, err := statement.ExecContext(ctx)
 	if err != nil {
 		return util.MakeErrorUnknown(err)
 	}
 
+	if expectedVersion != nil {
+		count, err := result.RowsAffected()
+		if err != nil {
+			return util.MakeErrorUnknown(err)
+		}
+		if count == 0 {
+			return util.MakeError(util.PRECONDITION_FAILED, fmt.Sprintf("Purchase Item %d was modified, expected version %d", itemId, *expectedVersion))
+		}
+	}
+
 	return nil
 }
 
-func (p Purchase) AddPurchaseItem(ctx context.Context, userId, purchaseId int64, item model.PurchaseItem) (model.Purchase, error) {
+// PURCHASE_ITEM_POSITION_GAP is the space left between item positions, the same used by the POSITION_PURCHASE_ITEM trigger.
+const PURCHASE_ITEM_POSITION_GAP = 65536
+
+type purchaseItemPosition struct {
+	Id       int64 `db:"id"`
+	Position int64 `db:"position"`
+}
+
+// MovePurchaseItem places the item halfway between the anchor and its neighbour, so only the moved item is written.
+// The other items are only renumbered when there is no room left between the two.
+func (p Purchase) MovePurchaseItem(ctx context.Context, purchaseId, itemId int64, move model.PurchaseItemMove) error {
+	before := move.Before != nil
+	anchorId := move.After
+	if before {
+		anchorId = move.Before
+	}
+
+	tx, err := p.DbConnection.NewSession(nil).Begin()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var items []purchaseItemPosition
+	_, err = tx.SelectBySql(`
+	SELECT id, position FROM PURCHASE_ITEM
+	WHERE purchase_id = ?
+	ORDER BY position, id
+	FOR UPDATE
+	`, purchaseId).LoadContext(ctx, &items)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	found := false
+	anchor := -1
+	others := make([]purchaseItemPosition, 0, len(items))
+	for _, item := range items {
+		if item.Id == itemId {
+			found = true
+			continue
+		}
+		if item.Id == *anchorId {
+			anchor = len(others)
+		}
+		others = append(others, item)
+	}
+	if !found {
+		return util.MakeError(util.NOT_FOUND, fmt.Sprintf("Purchase Item %d not found", itemId))
+	}
+	if anchor < 0 {
+		return util.MakeError(util.NOT_FOUND, fmt.Sprintf("Purchase Item %d not found", *anchorId))
+	}
+
+	position, ok := positionNextTo(others, anchor, before)
+	if !ok {
+		_, err = tx.UpdateBySql(`
+		UPDATE PURCHASE_ITEM pi SET position = ordered.rn * ?
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY position, id) rn
+			FROM purchase_item
+			WHERE purchase_id = ? AND id <> ?
+		) ordered
+		WHERE pi.id = ordered.id AND pi.position <> ordered.rn * ?
+		`, PURCHASE_ITEM_POSITION_GAP, purchaseId, itemId, PURCHASE_ITEM_POSITION_GAP).ExecContext(ctx)
+		if err != nil {
+			return util.MakeErrorUnknown(err)
+		}
+		for i := range others {
+			others[i].Position = int64(i+1) * PURCHASE_ITEM_POSITION_GAP
+		}
+		position, _ = positionNextTo(others, anchor, before)
+	}
+
+	_, err = tx.UpdateBySql(`
+	UPDATE PURCHASE_ITEM SET position = ? WHERE id = ?
+	`, position, itemId).ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
+
+// positionNextTo returns a free position right before or after the anchor, or false when its neighbour is too close.
+func positionNextTo(items []purchaseItemPosition, anchor int, before bool) (int64, bool) {
+	var low, high int64
+	if before {
+		high = items[anchor].Position
+		if anchor == 0 {
+			return high - PURCHASE_ITEM_POSITION_GAP, true
+		}
+		low = items[anchor-1].Position
+	} else {
+		low = items[anchor].Position
+		if anchor == len(items)-1 {
+			return low + PURCHASE_ITEM_POSITION_GAP, true
+		}
+		high = items[anchor+1].Position
+	}
+
+	if high-low < 2 {
+		return 0, false
+	}
+	return low + (high-low)/2, true
+}
+
+func (p Purchase) AddPurchaseItem(ctx context.Context, userId, purchaseId int64, item model.PurchaseItem) (model.PurchaseItem, error) {
 	statement := p.DbConnection.NewSession(nil).SelectBySql(`
-	INSERT INTO PURCHASE_ITEM(ID, PURCHASE_ID, PRODUCT_ID, QUANTITY, PRICE) 
-	values (default, ?, ?, ?, ?)`, purchaseId, item.Product.Id, item.Quantity, item.Price)
+	INSERT INTO PURCHASE_ITEM(ID, PURCHASE_ID, PRODUCT_ID, QUANTITY, PRICE, NOTES, PRIORITY, OPTIONAL) 
+	values (default, ?, ?, ?, ?, NULLIF(?, ''), COALESCE(?, ?), COALESCE(?, FALSE))
+	RETURNING ID`, purchaseId, item.Product.Id, item.Quantity, item.Price, item.Notes,
+		item.Priority, model.PURCHASE_ITEM_PRIORITY_NORMAL, item.Optional)
 
-	_, err := statement.LoadContext(ctx, &item)
+	var id int64
+	err := statement.LoadOneContext(ctx, &id)
 	if err != nil {
-		return model.Purchase{}, util.MakeErrorUnknown(err)
+		return model.PurchaseItem{}, util.MakeErrorUnknown(err)
+	}
+
+	return p.GetPurchaseItemById(ctx, userId, purchaseId, id)
+}
+
+// FindMergeablePurchaseItem returns the oldest unpurchased item of the product, matched by id or EAN, or nil when there is none.
+func (p Purchase) FindMergeablePurchaseItem(ctx context.Context, userId, purchaseId int64, product model.Product) (*model.PurchaseItem, error) {
+	statement := p.DbConnection.NewSession(nil).SelectBySql(FETCH_PURCHASE_ITEM+`
+	AND pi.purchase_id = ?
+	AND NOT pi.purchased
+	AND (p.id = ? OR (?::VARCHAR IS NOT NULL AND p.ean = ?))
+	ORDER BY pi.id
+	LIMIT 1
+	`, userId, purchaseId, product.Id, product.Ean, product.Ean)
+
+	var item repositoryModel.PurchaseItemProductInstance
+	err := statement.LoadOneContext(ctx, &item)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, util.MakeErrorUnknown(err)
 	}
 
-	return p.GetPurchaseById(ctx, userId, purchaseId)
+	result := item.ToPurchaseItem()
+	return &result, nil
+}
+
+// MergePurchaseItem adds the quantity of item to the stored one, replacing its price when a new one is given. The
+// higher priority wins, the item stays optional only when both are, and different notes are appended.
+func (p Purchase) MergePurchaseItem(ctx context.Context, userId, purchaseId, itemId int64, item model.PurchaseItem) (model.PurchaseItem, error) {
+	statement := p.DbConnection.NewSession(nil).UpdateBySql(`
+	UPDATE PURCHASE_ITEM SET quantity = quantity + ?, price = COALESCE(?, price),
+		priority = GREATEST(priority, COALESCE(?, priority)),
+		optional = optional AND COALESCE(?, optional),
+		notes = CASE
+			WHEN NULLIF(?, '') IS NULL OR notes = ? THEN notes
+			WHEN notes IS NULL THEN ?
+			ELSE LEFT(notes || E'\n' || ?, ?)
+		END
+		WHERE id = ? AND purchase_id = ?
+	`, item.Quantity, item.Price, item.Priority, item.Optional,
+		item.Notes, item.Notes, item.Notes, item.Notes, model.MAX_PURCHASE_ITEM_NOTES_LENGTH,
+		itemId, purchaseId)
+
+	_, err := statement.ExecContext(ctx)
+	if err != nil {
+		return model.PurchaseItem{}, util.MakeErrorUnknown(err)
+	}
+
+	return p.GetPurchaseItemById(ctx, userId, purchaseId, itemId)
+}
+
+// ConsolidatePurchaseItems folds the unpurchased duplicates of each product into the oldest item, which keeps its price
+// or takes the first known one, like MergePurchaseItem does with the details. It returns the ids of the kept items and
+// of the removed ones. The sum and the removal run in one statement over the same locked rows, so an item added
+// meanwhile is neither summed nor removed.
+func (p Purchase) ConsolidatePurchaseItems(ctx context.Context, purchaseId int64) ([]int64, []int64, error) {
+	var consolidated []struct {
+		Id      int64 `db:"id"`
+		Removed bool  `db:"removed"`
+	}
+	_, err := p.DbConnection.NewSession(nil).SelectBySql(`
+	WITH candidates AS (
+		SELECT id, product_id, quantity, price, notes, priority, optional
+		FROM purchase_item
+		WHERE purchase_id = ? AND NOT purchased
+		FOR UPDATE
+	), duplicates AS (
+		SELECT product_id,
+			MIN(id) keep_id,
+			ARRAY_AGG(id) ids,
+			SUM(quantity) total_quantity,
+			(ARRAY_AGG(price ORDER BY id) FILTER (WHERE price IS NOT NULL))[1] first_price,
+			LEFT(STRING_AGG(notes, E'\n' ORDER BY id), ?) notes,
+			MAX(priority) priority,
+			BOOL_AND(optional) optional
+		FROM candidates
+		GROUP BY product_id
+		HAVING COUNT(*) > 1
+	), merged AS (
+		UPDATE purchase_item pi SET quantity = d.total_quantity, price = COALESCE(pi.price, d.first_price),
+			notes = d.notes, priority = d.priority, optional = d.optional
+		FROM duplicates d
+		WHERE pi.id = d.keep_id
+		RETURNING pi.id
+	), removed AS (
+		DELETE FROM purchase_item pi
+		USING duplicates d
+		WHERE pi.id = ANY(d.ids) AND pi.id <> d.keep_id
+		RETURNING pi.id
+	)
+	SELECT id, FALSE removed FROM merged
+	UNION ALL
+	SELECT id, TRUE removed FROM removed
+	`, purchaseId, model.MAX_PURCHASE_ITEM_NOTES_LENGTH).LoadContext(ctx, &consolidated)
+	if err != nil {
+		return nil, nil, util.MakeErrorUnknown(err)
+	}
+
+	var merged, removed []int64
+	for _, item := range consolidated {
+		if item.Removed {
+			removed = append(removed, item.Id)
+		} else {
+			merged = append(merged, item.Id)
+		}
+	}
+
+	return merged, removed, nil
 }
 
 func (p Purchase) RemovePurchaseItem(ctx context.Context, userId, purchaseId int64, itemId int64) (model.Purchase, error) {
@@ -170,14 +585,155 @@ func (p Purchase) RemovePurchaseItem(ctx context.Context, userId, purchaseId int
 		return model.Purchase{}, util.MakeErrorUnknown(err)
 	}
 
-	return p.GetPurchaseByIdFetchItems(ctx, userId, purchaseId)
+	return p.GetPurchaseByIdFetchItems(ctx, userId, purchaseId, model.PURCHASE_ITEM_SORT_DEFAULT)
+}
+
+// ApplyPurchaseItemOperations runs the operations in a single transaction, each one behind a savepoint so a failed
+// operation leaves no trace. ADD and UPDATE operations with ProductChanged also write their product, see
+// saveItemProduct, so the product changes are rolled back with the items.
+func (p Purchase) ApplyPurchaseItemOperations(ctx context.Context, purchaseId int64, operations []model.PurchaseItemOperation, atomic bool) ([]model.PurchaseItemOperationResult, error) {
+	tx, err := p.DbConnection.NewSession(nil).Begin()
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	results := make([]model.PurchaseItemOperationResult, len(operations))
+	failed := false
+	for i, operation := range operations {
+		results[i] = model.PurchaseItemOperationResult{Index: i, Type: operation.Type, ItemId: operation.ItemId}
+		if failed {
+			results[i].Status = model.PURCHASE_ITEM_OPERATION_ROLLED_BACK
+			continue
+		}
+
+		if _, err = tx.ExecContext(ctx, "SAVEPOINT purchase_item_operation"); err != nil {
+			return nil, util.MakeErrorUnknown(err)
+		}
+
+		itemId, err := applyPurchaseItemOperation(ctx, tx, purchaseId, operation)
+		if err != nil {
+			if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT purchase_item_operation"); rollbackErr != nil {
+				return nil, util.MakeErrorUnknown(rollbackErr)
+			}
+			message := err.Error()
+			if mkError, ok := err.(*util.MarketListError); ok {
+				message = mkError.Message
+			}
+			results[i].Status = model.PURCHASE_ITEM_OPERATION_FAILED
+			results[i].Error = &message
+			failed = atomic
+			continue
+		}
+
+		results[i].Status = model.PURCHASE_ITEM_OPERATION_APPLIED
+		results[i].ItemId = itemId
+	}
+
+	if failed {
+		for i := range results {
+			if results[i].Status == model.PURCHASE_ITEM_OPERATION_APPLIED {
+				results[i].Status = model.PURCHASE_ITEM_OPERATION_ROLLED_BACK
+			}
+		}
+		return results, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+
+	return results, nil
+}
+
+func applyPurchaseItemOperation(ctx context.Context, tx *dbr.Tx, purchaseId int64, operation model.PurchaseItemOperation) (*int64, error) {
+	var result sql.Result
+	var err error
+
+	isItemWrite := operation.Type == model.PURCHASE_ITEM_OPERATION_ADD || operation.Type == model.PURCHASE_ITEM_OPERATION_UPDATE
+	if isItemWrite && operation.ProductChanged {
+		operation.Item.Product, err = saveItemProduct(ctx, tx, operation.Item.Product)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch operation.Type {
+	case model.PURCHASE_ITEM_OPERATION_ADD:
+		var id int64
+		err = tx.SelectBySql(`
+		INSERT INTO PURCHASE_ITEM(ID, PURCHASE_ID, PRODUCT_ID, QUANTITY, PRICE, PURCHASED, NOTES, PRIORITY, OPTIONAL)
+		values (default, ?, ?, ?, ?, ?, NULLIF(?, ''), COALESCE(?, ?), COALESCE(?, FALSE))
+		RETURNING ID`, purchaseId, operation.Item.Product.Id, operation.Item.Quantity, operation.Item.Price, operation.Item.Purchased,
+			operation.Item.Notes, operation.Item.Priority, model.PURCHASE_ITEM_PRIORITY_NORMAL, operation.Item.Optional).
+			LoadOneContext(ctx, &id)
+		if err != nil {
+			return nil, util.MakeErrorUnknown(err)
+		}
+		return &id, nil
+	case model.PURCHASE_ITEM_OPERATION_UPDATE:
+		result, err = tx.UpdateBySql(`
+		UPDATE PURCHASE_ITEM SET purchased = ?, quantity = ?, price = ?, product_id = ?,
+			notes = NULLIF(COALESCE(?, notes), ''), priority = COALESCE(?, priority), optional = COALESCE(?, optional)
+			WHERE id = ? AND purchase_id = ? AND (?::BIGINT IS NULL OR version = ?)
+		`, operation.Item.Purchased, operation.Item.Quantity, operation.Item.Price, operation.Item.Product.Id,
+			operation.Item.Notes, operation.Item.Priority, operation.Item.Optional,
+			operation.ItemId, purchaseId, operation.Version, operation.Version).ExecContext(ctx)
+	case model.PURCHASE_ITEM_OPERATION_CHECK:
+		result, err = tx.UpdateBySql(`
+		UPDATE PURCHASE_ITEM SET purchased = ?
+			WHERE id = ? AND purchase_id = ? AND (?::BIGINT IS NULL OR version = ?)
+		`, operation.Purchased == nil || *operation.Purchased, operation.ItemId, purchaseId, operation.Version, operation.Version).ExecContext(ctx)
+	case model.PURCHASE_ITEM_OPERATION_REMOVE:
+		result, err = tx.DeleteBySql(`
+		DELETE FROM PURCHASE_ITEM WHERE id = ? AND purchase_id = ?
+		`, operation.ItemId, purchaseId).ExecContext(ctx)
+	default:
+		return nil, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("invalid operation type %s", operation.Type))
+	}
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+	if count > 0 {
+		return operation.ItemId, nil
+	}
+
+	var exists bool
+	err = tx.SelectBySql(`
+	SELECT EXISTS (SELECT 1 FROM PURCHASE_ITEM WHERE id = ? AND purchase_id = ?)
+	`, operation.ItemId, purchaseId).LoadOneContext(ctx, &exists)
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+	// Without a version only a missing row leaves nothing to change, even when it disappeared after the update.
+	if !exists || operation.Version == nil {
+		return nil, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Purchase Item %d not found", *operation.ItemId))
+	}
+	return nil, util.MakeError(util.PRECONDITION_FAILED, fmt.Sprintf("Purchase Item %d was modified, expected version %d", *operation.ItemId, *operation.Version))
 }
 
-func (p Purchase) getAllPurchaseItemByPurchaseId(ctx context.Context, userId, purchaseId int64, purchase model.Purchase) ([]model.PurchaseItem, error) {
+// purchaseItemSortColumns keeps purchased items last in every order. The walking order is applied by the service
+// on top of the default one.
+var purchaseItemSortColumns = map[model.PurchaseItemSort]string{
+	model.PURCHASE_ITEM_SORT_DEFAULT: "purchase_item_purchased, purchase_item_priority DESC, purchase_item_optional, purchase_item_quantity ASC",
+	model.PURCHASE_ITEM_SORT_MANUAL:  "purchase_item_purchased, purchase_item_position, purchase_item_id",
+	model.PURCHASE_ITEM_SORT_WALKING: "purchase_item_purchased, purchase_item_priority DESC, purchase_item_optional, purchase_item_quantity ASC",
+}
+
+func (p Purchase) getAllPurchaseItemByPurchaseId(ctx context.Context, userId, purchaseId int64, purchase model.Purchase, sort model.PurchaseItemSort) ([]model.PurchaseItem, error) {
+	sortColumns, ok := purchaseItemSortColumns[sort]
+	if !ok {
+		return []model.PurchaseItem{}, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("invalid sort %s", sort))
+	}
 	statement := p.DbConnection.NewSession(nil).SelectBySql(FETCH_PURCHASE_ITEM+`
 	AND pi.purchase_id = ?
-	ORDER BY purchase_item_purchased, purchase_item_quantity ASC
-	`, userId, purchaseId)
+	ORDER BY `+sortColumns, userId, purchaseId)
 
 	var items []repositoryModel.PurchaseItemProductInstance
 	_, err := statement.LoadContext(ctx, &items)
@@ -195,15 +751,16 @@ func (p Purchase) getAllPurchaseItemByPurchaseId(ctx context.Context, userId, pu
 	return results, nil
 }
 
-func (p Purchase) GetPurchaseByIdFetchItems(ctx context.Context, userId, id int64) (model.Purchase, error) {
-	return p.getPurchaseByIdInternal(ctx, userId, id, true)
+func (p Purchase) GetPurchaseByIdFetchItems(ctx context.Context, userId, id int64, sort model.PurchaseItemSort) (model.Purchase, error) {
+	return p.getPurchaseByIdInternal(ctx, userId, id, &sort)
 }
 
 func (p Purchase) GetPurchaseById(ctx context.Context, userId, id int64) (model.Purchase, error) {
-	return p.getPurchaseByIdInternal(ctx, userId, id, false)
+	return p.getPurchaseByIdInternal(ctx, userId, id, nil)
 }
 
-func (p Purchase) getPurchaseByIdInternal(ctx context.Context, userId, id int64, fetchItems bool) (model.Purchase, error) {
+// getPurchaseByIdInternal only fetches the items when a sort for them is given.
+func (p Purchase) getPurchaseByIdInternal(ctx context.Context, userId, id int64, sort *model.PurchaseItemSort) (model.Purchase, error) {
 	statement := p.DbConnection.NewSession(nil).SelectBySql(FETCH_PURCHASE+`
 		  AND p.ID = ?
 		`, userId, id)
@@ -219,12 +776,18 @@ func (p Purchase) getPurchaseByIdInternal(ctx context.Context, userId, id int64,
 	}
 
 	result := purchase.ToPurchase()
-	if !fetchItems {
+	tags, err := p.getTagsByPurchaseIds(ctx, userId, []int64{id})
+	if err != nil {
+		return model.Purchase{}, err
+	}
+	result.Tags = tags[id]
+
+	if sort == nil {
 		return result, nil
 	}
-	result.Items, err = p.getAllPurchaseItemByPurchaseId(ctx, userId, id, result)
+	result.Items, err = p.getAllPurchaseItemByPurchaseId(ctx, userId, id, result, *sort)
 	if err != nil {
-		return model.Purchase{}, util.MakeErrorUnknown(err)
+		return model.Purchase{}, err
 	}
 
 	return result, nil
@@ -246,19 +809,196 @@ func (p Purchase) GetPurchaseItemById(ctx context.Context, userId, purchaseId in
 	return item.ToPurchaseItem(), nil
 }
 
-func (p Purchase) ListPurchase(ctx context.Context, userId int64) ([]model.Purchase, error) {
-	statement := p.DbConnection.NewSession(nil).SelectBySql(FETCH_PURCHASE, userId)
+var purchaseSortColumns = map[model.PurchaseSort]string{
+	model.PURCHASE_SORT_CREATED_AT: "p.created_at",
+	model.PURCHASE_SORT_NAME:       "p.name",
+	model.PURCHASE_SORT_TOTAL:      "totals.total_expected",
+}
+
+func (p Purchase) ListPurchase(ctx context.Context, userId int64, filter model.PurchaseFilter) (model.PurchasePage, error) {
+	sortColumn, ok := purchaseSortColumns[filter.Sort]
+	if !ok {
+		return model.PurchasePage{}, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("invalid sort %s", filter.Sort))
+	}
+
+	query := strings.Builder{}
+	query.WriteString(FETCH_PURCHASE)
+	args := []interface{}{userId}
+
+	if filter.MarketId != nil {
+		query.WriteString("	AND p.market_id = ?\n")
+		args = append(args, *filter.MarketId)
+	}
+	if filter.TagId != nil {
+		query.WriteString("	AND EXISTS (SELECT 1 FROM tag_purchase tp WHERE tp.purchase_id = p.id AND tp.tag_id = ?)\n")
+		args = append(args, *filter.TagId)
+	}
+	if filter.IsFavorite != nil {
+		query.WriteString("	AND p.is_favorite = ?\n")
+		args = append(args, *filter.IsFavorite)
+	}
+	if filter.CreatedFrom != nil {
+		query.WriteString("	AND p.created_at >= ?\n")
+		args = append(args, *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query.WriteString("	AND p.created_at < ?\n")
+		args = append(args, *filter.CreatedTo)
+	}
+
+	direction, comparator := "ASC", ">"
+	if filter.Descending {
+		direction, comparator = "DESC", "<"
+	}
+
+	if filter.Cursor != nil {
+		cursor, err := repositoryModel.DecodePurchaseCursor(*filter.Cursor, filter)
+		if err != nil {
+			return model.PurchasePage{}, err
+		}
+		sortValue, err := cursor.SortValue()
+		if err != nil {
+			return model.PurchasePage{}, err
+		}
+		query.WriteString(fmt.Sprintf("	AND (%s, p.id) %s (?, ?)\n", sortColumn, comparator))
+		args = append(args, sortValue, cursor.Id)
+	}
+
+	query.WriteString(fmt.Sprintf("	ORDER BY %s %s, p.id %s\n	LIMIT ?", sortColumn, direction, direction))
+	args = append(args, filter.Limit+1)
+
+	statement := p.DbConnection.NewSession(nil).SelectBySql(query.String(), args...)
 
 	var items []repositoryModel.PurchaseEntity
 	_, err := statement.LoadContext(ctx, &items)
 	if err != nil {
-		return []model.Purchase{}, util.MakeErrorUnknown(err)
+		return model.PurchasePage{}, util.MakeErrorUnknown(err)
+	}
+
+	var page model.PurchasePage
+	if len(items) > filter.Limit {
+		items = items[:filter.Limit]
+		nextCursor := repositoryModel.CreatePurchaseCursor(filter, items[len(items)-1]).Encode()
+		page.NextCursor = &nextCursor
 	}
 
 	results := make([]model.Purchase, len(items))
+	purchaseIds := make([]int64, len(items))
 
 	for i, v := range items {
 		results[i] = v.ToPurchase()
+		purchaseIds[i] = *v.Id
+	}
+
+	tags, err := p.getTagsByPurchaseIds(ctx, userId, purchaseIds)
+	if err != nil {
+		return model.PurchasePage{}, err
+	}
+	for i := range results {
+		results[i].Tags = tags[*results[i].Id]
+	}
+	page.Items = results
+
+	return page, nil
+}
+
+// ListPurchasesByIds returns the purchases of the user with the given ids, or all of them when ids is nil.
+func (p Purchase) ListPurchasesByIds(ctx context.Context, userId int64, ids []int64) ([]model.Purchase, error) {
+	if ids != nil && len(ids) == 0 {
+		return []model.Purchase{}, nil
+	}
+
+	query := FETCH_PURCHASE
+	args := []interface{}{userId}
+	if ids != nil {
+		query += "	AND p.id IN ?\n"
+		args = append(args, ids)
+	}
+	query += "	ORDER BY p.id"
+
+	var items []repositoryModel.PurchaseEntity
+	_, err := p.DbConnection.NewSession(nil).SelectBySql(query, args...).LoadContext(ctx, &items)
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+
+	results := make([]model.Purchase, len(items))
+	purchaseIds := make([]int64, len(items))
+	for i, v := range items {
+		results[i] = v.ToPurchase()
+		purchaseIds[i] = *v.Id
+	}
+
+	tags, err := p.getTagsByPurchaseIds(ctx, userId, purchaseIds)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Tags = tags[*results[i].Id]
+	}
+
+	return results, nil
+}
+
+// ListPurchaseItems returns the items of the user's purchases that either belong to one of purchaseIds or have one of itemIds.
+func (p Purchase) ListPurchaseItems(ctx context.Context, userId int64, purchaseIds, itemIds []int64) ([]model.PurchaseItem, error) {
+	if len(purchaseIds) == 0 && len(itemIds) == 0 {
+		return []model.PurchaseItem{}, nil
+	}
+
+	// An empty IN list is invalid SQL, so a missing side matches the impossible id 0.
+	if len(purchaseIds) == 0 {
+		purchaseIds = []int64{0}
+	}
+	if len(itemIds) == 0 {
+		itemIds = []int64{0}
+	}
+
+	statement := p.DbConnection.NewSession(nil).SelectBySql(FETCH_PURCHASE_ITEM+`
+	AND (pi.purchase_id IN ? OR pi.id IN ?)
+	ORDER BY pi.purchase_id, pi.id
+	`, userId, purchaseIds, itemIds)
+
+	var items []repositoryModel.PurchaseItemProductInstance
+	_, err := statement.LoadContext(ctx, &items)
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+
+	results := make([]model.PurchaseItem, len(items))
+	for i, v := range items {
+		results[i] = v.ToPurchaseItem()
+	}
+
+	return results, nil
+}
+
+func (p Purchase) getTagsByPurchaseIds(ctx context.Context, userId int64, purchaseIds []int64) (map[int64][]model.Tag, error) {
+	results := make(map[int64][]model.Tag)
+	if len(purchaseIds) == 0 {
+		return results, nil
+	}
+
+	statement := p.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT tp.purchase_id purchase_id,
+		t.id tag_id,
+		t.name tag_name,
+		t.user_id tag_user_id,
+		t.created_at tag_created_at
+	FROM tag_purchase tp
+		INNER JOIN tag t ON t.id = tp.tag_id AND t.user_id = ?
+	WHERE tp.purchase_id IN ?
+	ORDER BY t.name
+	`, userId, purchaseIds)
+
+	var tags []repositoryModel.PurchaseTagInstance
+	_, err := statement.LoadContext(ctx, &tags)
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+
+	for _, tag := range tags {
+		results[tag.PurchaseId] = append(results[tag.PurchaseId], tag.ToTag())
 	}
 
 	return results, nil
@@ -0,0 +1,104 @@
+package repository
+
+import "testing"
+
+func positions(values ...int64) []purchaseItemPosition {
+	items := make([]purchaseItemPosition, len(values))
+	for i, value := range values {
+		items[i] = purchaseItemPosition{Id: int64(i + 1), Position: value}
+	}
+	return items
+}
+
+func TestPositionNextTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []purchaseItemPosition
+		anchor   int
+		before   bool
+		position int64
+		ok       bool
+	}{
+		{
+			name:     "before the first item",
+			items:    positions(65536, 131072),
+			anchor:   0,
+			before:   true,
+			position: 0,
+			ok:       true,
+		},
+		{
+			name:     "after the last item",
+			items:    positions(65536, 131072),
+			anchor:   1,
+			before:   false,
+			position: 196608,
+			ok:       true,
+		},
+		{
+			name:     "before an item in the middle",
+			items:    positions(65536, 131072, 196608),
+			anchor:   1,
+			before:   true,
+			position: 98304,
+			ok:       true,
+		},
+		{
+			name:     "after an item in the middle",
+			items:    positions(65536, 131072, 196608),
+			anchor:   1,
+			before:   false,
+			position: 163840,
+			ok:       true,
+		},
+		{
+			name:     "only item",
+			items:    positions(65536),
+			anchor:   0,
+			before:   false,
+			position: 131072,
+			ok:       true,
+		},
+		{
+			name:     "smallest gap left",
+			items:    positions(10, 12),
+			anchor:   0,
+			before:   false,
+			position: 11,
+			ok:       true,
+		},
+		{
+			name:   "gap exhausted after the anchor",
+			items:  positions(10, 11),
+			anchor: 0,
+			before: false,
+			ok:     false,
+		},
+		{
+			name:   "gap exhausted before the anchor",
+			items:  positions(10, 11),
+			anchor: 1,
+			before: true,
+			ok:     false,
+		},
+		{
+			name:   "same position",
+			items:  positions(10, 10),
+			anchor: 0,
+			before: false,
+			ok:     false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			position, ok := positionNextTo(test.items, test.anchor, test.before)
+			if ok != test.ok {
+				t.Fatalf("ok = %v, want %v", ok, test.ok)
+			}
+			if ok && position != test.position {
+				t.Errorf("position = %d, want %d", position, test.position)
+			}
+		})
+	}
+}
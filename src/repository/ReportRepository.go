@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/gocraft/dbr/v2"
+	"github.com/lib/pq"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+	"strings"
+)
+
+type ReportRepository interface {
+	ListMonthlyPrices(ctx context.Context, userId int64, timezone string, filter model.InflationFilter) ([]model.MonthlyPrice, error)
+	ListMonthlyTagPrices(ctx context.Context, userId int64, timezone string, filter model.InflationFilter) ([]model.MonthlyPrice, error)
+	ListSpending(ctx context.Context, userId int64, timezone string, filter model.SpendingFilter) ([]model.SpendingPeriod, error)
+}
+
+type Report struct {
+	DbConnection *dbr.Connection
+}
+
+func CreateReportRepository(connection *dbr.Connection) ReportRepository {
+	return &Report{
+		DbConnection: connection,
+	}
+}
+
+// reportError turns the rejection of a timezone the database does not know into an input error.
+func reportError(err error, timezone string) error {
+	if pqError, ok := err.(*pq.Error); ok && pqError.Code == "22023" {
+		return util.MakeError(util.INVALID_INPUT, fmt.Sprintf("invalid timezone %s", timezone))
+	}
+	return util.MakeErrorUnknown(err)
+}
+
+// priceObservationsBetween is FETCH_PRICE_OBSERVATION with the observation time moved to the timezone of the user,
+// restricted to the range of the filter in that timezone.
+func priceObservationsBetween(userId int64, timezone string, filter model.InflationFilter) (string, []interface{}) {
+	query := strings.Builder{}
+	query.WriteString(`SELECT o.*, o.observed_at AT TIME ZONE 'UTC' AT TIME ZONE ? local_observed_at FROM (
+	`)
+	query.WriteString(FETCH_PRICE_OBSERVATION)
+	query.WriteString(`	) o
+	WHERE TRUE
+	`)
+	args := []interface{}{timezone, userId}
+
+	if filter.From != nil {
+		query.WriteString("	AND o.observed_at AT TIME ZONE 'UTC' AT TIME ZONE ? >= ?\n")
+		args = append(args, timezone, *filter.From)
+	}
+	if filter.To != nil {
+		query.WriteString("	AND o.observed_at AT TIME ZONE 'UTC' AT TIME ZONE ? < ?\n")
+		args = append(args, timezone, *filter.To)
+	}
+
+	return query.String(), args
+}
+
+func (r Report) ListMonthlyPrices(ctx context.Context, userId int64, timezone string, filter model.InflationFilter) ([]model.MonthlyPrice, error) {
+	observations, args := priceObservationsBetween(userId, timezone, filter)
+	statement := r.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT DATE_TRUNC('month', o.local_observed_at) AS month,
+	       o.product_id,
+	       pr.name product_name,
+	       o.market_id,
+	       o.market_name,
+	       c.id category_id,
+	       c.name category_name,
+	       SUM(o.price * o.quantity) spent,
+	       SUM(o.quantity) quantity
+	FROM (
+	`+observations+`
+	) o
+		INNER JOIN product pr ON pr.id = o.product_id
+		LEFT JOIN category c ON c.id = pr.category_id
+	WHERE o.quantity > 0
+	GROUP BY 1, o.product_id, pr.name, o.market_id, o.market_name, c.id, c.name
+	ORDER BY 1
+	`, args...)
+
+	var prices []model.MonthlyPrice
+	_, err := statement.LoadContext(ctx, &prices)
+	if err != nil {
+		return []model.MonthlyPrice{}, reportError(err, timezone)
+	}
+
+	return prices, nil
+}
+
+// ListMonthlyTagPrices is ListMonthlyPrices split by the tags the user put on the purchases, without the market split.
+func (r Report) ListMonthlyTagPrices(ctx context.Context, userId int64, timezone string, filter model.InflationFilter) ([]model.MonthlyPrice, error) {
+	observations, args := priceObservationsBetween(userId, timezone, filter)
+	args = append(args, userId)
+	statement := r.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT DATE_TRUNC('month', o.local_observed_at) AS month,
+	       o.product_id,
+	       pr.name product_name,
+	       t.id tag_id,
+	       t.name tag_name,
+	       SUM(o.price * o.quantity) spent,
+	       SUM(o.quantity) quantity
+	FROM (
+	`+observations+`
+	) o
+		INNER JOIN product pr ON pr.id = o.product_id
+		INNER JOIN tag_purchase tp ON tp.purchase_id = o.purchase_id
+		INNER JOIN tag t ON t.id = tp.tag_id AND t.user_id = ?
+	WHERE o.quantity > 0
+	GROUP BY 1, o.product_id, pr.name, t.id, t.name
+	ORDER BY 1
+	`, args...)
+
+	var prices []model.MonthlyPrice
+	_, err := statement.LoadContext(ctx, &prices)
+	if err != nil {
+		return []model.MonthlyPrice{}, reportError(err, timezone)
+	}
+
+	return prices, nil
+}
+
+// ListSpending sums the purchases of the user by period. The creation time of the purchases is stored in UTC, so it is
+// moved to the timezone of the user before the periods are cut.
+func (r Report) ListSpending(ctx context.Context, userId int64, timezone string, filter model.SpendingFilter) ([]model.SpendingPeriod, error) {
+	query := strings.Builder{}
+	query.WriteString(`
+	SELECT TO_CHAR(DATE_TRUNC(?, s.local_created_at), 'YYYY-MM-DD') period,
+	       SUM(s.total_spent) total_spent,
+	       SUM(s.total_expected) total_expected,
+	       COUNT(*) purchase_count,
+	       COUNT(*) FILTER (WHERE s.purchased_item_count > 0) trips,
+	       SUM(s.purchased_quantity) purchased_quantity
+	FROM (
+		SELECT p.created_at AT TIME ZONE 'UTC' AT TIME ZONE ? local_created_at,
+		       totals.*
+		FROM purchase p
+			INNER JOIN purchase_user pu ON pu.purchase_id = p.id AND pu.user_id = ?
+			LEFT JOIN LATERAL (
+				SELECT COALESCE(SUM(pi.price * pi.quantity), 0) total_expected,
+				       COALESCE(SUM(pi.price * pi.quantity) FILTER (WHERE pi.purchased), 0) total_spent,
+				       COUNT(pi.id) FILTER (WHERE pi.purchased) purchased_item_count,
+				       COALESCE(SUM(pi.quantity) FILTER (WHERE pi.purchased), 0) purchased_quantity
+				FROM purchase_item pi
+				WHERE pi.purchase_id = p.id
+			) totals ON TRUE
+	) s
+	WHERE TRUE
+`)
+	args := []interface{}{string(filter.Granularity), timezone, userId}
+
+	if filter.From != nil {
+		query.WriteString("	AND s.local_created_at >= ?\n")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		query.WriteString("	AND s.local_created_at < ?\n")
+		args = append(args, *filter.To)
+	}
+	query.WriteString("	GROUP BY 1\n	ORDER BY 1\n")
+
+	var periods []model.SpendingPeriod
+	_, err := r.DbConnection.NewSession(nil).SelectBySql(query.String(), args...).LoadContext(ctx, &periods)
+	if err != nil {
+		return []model.SpendingPeriod{}, reportError(err, timezone)
+	}
+
+	return periods, nil
+}
@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gocraft/dbr/v2"
+	"github.com/lib/pq"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+	"strings"
+	"time"
+)
+
+type SyncRepository interface {
+	GetSyncToken(ctx context.Context) (int64, error)
+	ListChanges(ctx context.Context, userId, from, to int64) ([]model.SyncChange, error)
+	ApplyPurchaseItemChanges(ctx context.Context, userId, purchaseId, itemId int64, changes model.PurchaseItemChanges, changedAt time.Time) ([]string, []string, error)
+	ApplyPurchaseChanges(ctx context.Context, userId, purchaseId int64, changes model.PurchaseUpdate, changedAt time.Time) ([]string, []string, error)
+	GetMutationResult(ctx context.Context, userId int64, clientId string) (*model.SyncMutationResult, error)
+	SaveMutationResult(ctx context.Context, userId int64, result model.SyncMutationResult) error
+	GetPrunedSyncToken(ctx context.Context) (int64, error)
+	PruneChanges(ctx context.Context, retention, interval time.Duration) error
+}
+
+type Sync struct {
+	DbConnection *dbr.Connection
+}
+
+func CreateSyncRepository(connection *dbr.Connection) SyncRepository {
+	return &Sync{
+		DbConnection: connection,
+	}
+}
+
+type syncChangeEntity struct {
+	Entity        string `db:"entity"`
+	EntityId      int64  `db:"entity_id"`
+	PurchaseId    int64  `db:"purchase_id"`
+	OwnMembership bool   `db:"own_membership"`
+}
+
+// syncField is a column that is merged field by field, with the column holding the time of its last change.
+type syncField struct {
+	name      string
+	column    string
+	updatedAt string
+	value     interface{}
+}
+
+// GetSyncToken returns the oldest transaction id still in progress.
+// Every change log row written by an older transaction is already committed, so the feed can safely advance up to it.
+func (s Sync) GetSyncToken(ctx context.Context) (int64, error) {
+	var token int64
+	err := s.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT TXID_SNAPSHOT_XMIN(TXID_CURRENT_SNAPSHOT())
+	`).LoadOneContext(ctx, &token)
+	if err != nil {
+		return 0, util.MakeErrorUnknown(err)
+	}
+
+	return token, nil
+}
+
+func (s Sync) ListChanges(ctx context.Context, userId, from, to int64) ([]model.SyncChange, error) {
+	statement := s.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT DISTINCT c.entity entity,
+		c.entity_id entity_id,
+		c.purchase_id purchase_id,
+		(c.entity = 'PURCHASE_USER' AND c.user_id = ?) own_membership
+	FROM change_log c
+	WHERE c.tx_id >= ? AND c.tx_id < ?
+		AND (c.purchase_id IN (SELECT pu.purchase_id FROM purchase_user pu WHERE pu.user_id = ?)
+			OR (c.entity = 'PURCHASE_USER' AND c.user_id = ?))
+	ORDER BY c.purchase_id, c.entity, c.entity_id
+	`, userId, from, to, userId, userId)
+
+	var changes []syncChangeEntity
+	_, err := statement.LoadContext(ctx, &changes)
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+
+	results := make([]model.SyncChange, len(changes))
+	for i, change := range changes {
+		results[i] = model.SyncChange{
+			Entity:        model.SyncEntity(change.Entity),
+			EntityId:      change.EntityId,
+			PurchaseId:    change.PurchaseId,
+			OwnMembership: change.OwnMembership,
+		}
+	}
+
+	return results, nil
+}
+
+// ApplyPurchaseItemChanges merges the changes into the item with last-writer-wins per field:
+// a field is only written when changedAt is newer than its last change, otherwise it is reported as a conflict.
+func (s Sync) ApplyPurchaseItemChanges(ctx context.Context, userId, purchaseId, itemId int64, changes model.PurchaseItemChanges, changedAt time.Time) ([]string, []string, error) {
+	var fields []syncField
+	if changes.Purchased != nil {
+		fields = append(fields, syncField{"purchased", "purchased", "purchased_updated_at", *changes.Purchased})
+	}
+	if changes.Quantity != nil {
+		fields = append(fields, syncField{"quantity", "quantity", "quantity_updated_at", *changes.Quantity})
+	}
+	if changes.Price != nil {
+		fields = append(fields, syncField{"price", "price", "price_updated_at", *changes.Price})
+	}
+
+	notFound := util.MakeError(util.NOT_FOUND, fmt.Sprintf("Purchase Item %d not found", itemId))
+	return s.applyChanges(ctx, "purchase_item", fields, changedAt, notFound, `
+		FROM purchase_item t
+			INNER JOIN purchase_user pu ON pu.purchase_id = t.purchase_id AND pu.user_id = ?
+		WHERE t.id = ? AND t.purchase_id = ?
+	`, userId, itemId, purchaseId)
+}
+
+// ApplyPurchaseChanges merges the changes into the purchase with the same per field policy as ApplyPurchaseItemChanges.
+func (s Sync) ApplyPurchaseChanges(ctx context.Context, userId, purchaseId int64, changes model.PurchaseUpdate, changedAt time.Time) ([]string, []string, error) {
+	var fields []syncField
+	if changes.Name != nil {
+		fields = append(fields, syncField{"name", "name", "name_updated_at", *changes.Name})
+	}
+	if changes.MarketId != nil {
+		fields = append(fields, syncField{"marketId", "market_id", "market_id_updated_at", *changes.MarketId})
+	}
+	if changes.IsFavorite != nil {
+		fields = append(fields, syncField{"isFavorite", "is_favorite", "is_favorite_updated_at", *changes.IsFavorite})
+	}
+
+	notFound := util.MakeError(util.NOT_FOUND, fmt.Sprintf("Purchase %d not found", purchaseId))
+	return s.applyChanges(ctx, "purchase", fields, changedAt, notFound, `
+		FROM purchase t
+			INNER JOIN purchase_user pu ON pu.purchase_id = t.id AND pu.user_id = ?
+		WHERE t.id = ?
+	`, userId, purchaseId)
+}
+
+// applyChanges locks the row selected by from (aliased t), compares the last change time of every field
+// with changedAt and writes the fields that win together with their new change time.
+func (s Sync) applyChanges(ctx context.Context, table string, fields []syncField, changedAt time.Time, notFound error, from string, args ...interface{}) ([]string, []string, error) {
+	tx, err := s.DbConnection.NewSession(nil).Begin()
+	if err != nil {
+		return nil, nil, util.MakeErrorUnknown(err)
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	columns := []string{"t.id"}
+	for _, field := range fields {
+		columns = append(columns, "COALESCE(t."+field.updatedAt+", 'epoch')")
+	}
+
+	rows, err := tx.SelectBySql("SELECT "+strings.Join(columns, ", ")+from+" FOR UPDATE OF t", args...).RowsContext(ctx)
+	if err != nil {
+		return nil, nil, util.MakeErrorUnknown(err)
+	}
+
+	var id int64
+	updatedAt := make([]time.Time, len(fields))
+	destinations := []interface{}{&id}
+	for i := range updatedAt {
+		destinations = append(destinations, &updatedAt[i])
+	}
+
+	found := rows.Next()
+	if found {
+		err = rows.Scan(destinations...)
+	}
+	_ = rows.Close()
+	if err != nil {
+		return nil, nil, util.MakeErrorUnknown(err)
+	}
+	if !found {
+		return nil, nil, notFound
+	}
+
+	applied := []string{}
+	conflicts := []string{}
+	assignments := []string{}
+	var values []interface{}
+	for i, field := range fields {
+		if !changedAt.After(updatedAt[i]) {
+			conflicts = append(conflicts, field.name)
+			continue
+		}
+		applied = append(applied, field.name)
+		assignments = append(assignments, field.column+" = ?", field.updatedAt+" = ?")
+		values = append(values, field.value, changedAt)
+	}
+
+	if len(assignments) > 0 {
+		values = append(values, id)
+		_, err = tx.UpdateBySql("UPDATE "+table+" SET "+strings.Join(assignments, ", ")+" WHERE id = ?", values...).ExecContext(ctx)
+		if err != nil {
+			if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23503" {
+				return nil, nil, util.MakeError(util.INVALID_INPUT, pqError.Detail)
+			}
+			return nil, nil, util.MakeErrorUnknown(err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, nil, util.MakeErrorUnknown(err)
+	}
+
+	return applied, conflicts, nil
+}
+
+func (s Sync) GetMutationResult(ctx context.Context, userId int64, clientId string) (*model.SyncMutationResult, error) {
+	var encoded string
+	err := s.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT result FROM sync_mutation WHERE user_id = ? AND client_id = ?
+	`, userId, clientId).LoadOneContext(ctx, &encoded)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, util.MakeErrorUnknown(err)
+	}
+
+	var result model.SyncMutationResult
+	err = json.Unmarshal([]byte(encoded), &result)
+	if err != nil {
+		return nil, util.MakeErrorUnknown(err)
+	}
+
+	return &result, nil
+}
+
+func (s Sync) SaveMutationResult(ctx context.Context, userId int64, result model.SyncMutationResult) error {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	_, err = s.DbConnection.NewSession(nil).InsertBySql(`
+	INSERT INTO sync_mutation (user_id, client_id, result) VALUES (?, ?, ?)
+	ON CONFLICT (user_id, client_id) DO NOTHING
+	`, userId, result.ClientId, string(encoded)).ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
+
+// GetPrunedSyncToken returns the oldest sync token whose changes are all still logged.
+func (s Sync) GetPrunedSyncToken(ctx context.Context) (int64, error) {
+	var token int64
+	err := s.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT pruned_tx_id FROM sync_retention
+	`).LoadOneContext(ctx, &token)
+	if err != nil {
+		return 0, util.MakeErrorUnknown(err)
+	}
+
+	return token, nil
+}
+
+// PruneChanges deletes the change log entries and the saved mutation results older than retention, at most once per
+// interval across every server. The pruned sync token moves past the newest deleted transaction.
+func (s Sync) PruneChanges(ctx context.Context, retention, interval time.Duration) error {
+	tx, err := s.DbConnection.NewSession(nil).Begin()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	result, err := tx.UpdateBySql(`
+	UPDATE sync_retention SET pruned_at = NOW()
+		WHERE pruned_at IS NULL OR pruned_at < NOW() - ? * INTERVAL '1 second'
+	`, interval.Seconds()).ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+	if count == 0 {
+		return nil
+	}
+
+	_, err = tx.UpdateBySql(`
+	WITH pruned AS (
+		DELETE FROM change_log WHERE changed_at < NOW() - ? * INTERVAL '1 second'
+		RETURNING tx_id
+	)
+	UPDATE sync_retention SET pruned_tx_id = GREATEST(pruned_tx_id, (SELECT MAX(tx_id) + 1 FROM pruned))
+	`, retention.Seconds()).ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	_, err = tx.DeleteBySql(`
+	DELETE FROM sync_mutation WHERE created_at < NOW() - ? * INTERVAL '1 second'
+	`, retention.Seconds()).ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
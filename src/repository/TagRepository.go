@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gocraft/dbr/v2"
+	"github.com/lib/pq"
+	"github.com/ronistone/market-list/src/model"
+	repositoryModel "github.com/ronistone/market-list/src/repository/model"
+	"github.com/ronistone/market-list/src/util"
+)
+
+type TagRepository interface {
+	CreateTag(ctx context.Context, userId int64, tag model.Tag) (model.Tag, error)
+	UpdateTag(ctx context.Context, userId int64, tag model.Tag) (model.Tag, error)
+	DeleteTag(ctx context.Context, userId, id int64) error
+	GetTagById(ctx context.Context, userId, id int64) (model.Tag, error)
+	ListTags(ctx context.Context, userId int64) ([]model.Tag, error)
+	AttachTag(ctx context.Context, purchaseId, tagId int64) error
+	DetachTag(ctx context.Context, userId, purchaseId, tagId int64) error
+}
+
+type Tag struct {
+	DbConnection *dbr.Connection
+}
+
+func CreateTagRepository(connection *dbr.Connection) TagRepository {
+	return &Tag{
+		DbConnection: connection,
+	}
+}
+
+func (t Tag) CreateTag(ctx context.Context, userId int64, tag model.Tag) (model.Tag, error) {
+	statement := t.DbConnection.NewSession(nil).SelectBySql(`
+	INSERT INTO TAG(name, user_id)
+		VALUES (?, ?)
+	RETURNING *
+	`, tag.Name, userId)
+
+	var created repositoryModel.Tag
+	err := statement.LoadOneContext(ctx, &created)
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+			return model.Tag{}, util.MakeError(util.ALREADY_EXISTS, fmt.Sprintf("Tag %s already exists", tag.Name))
+		}
+		return model.Tag{}, util.MakeErrorUnknown(err)
+	}
+
+	return created.ToTag(), nil
+}
+
+func (t Tag) UpdateTag(ctx context.Context, userId int64, tag model.Tag) (model.Tag, error) {
+	statement := t.DbConnection.NewSession(nil).SelectBySql(`
+	UPDATE TAG SET name = ?
+		WHERE id = ? AND user_id = ?
+	RETURNING *
+	`, tag.Name, tag.Id, userId)
+
+	var updated repositoryModel.Tag
+	err := statement.LoadOneContext(ctx, &updated)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return model.Tag{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Tag %d not found", tag.Id))
+		}
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+			return model.Tag{}, util.MakeError(util.ALREADY_EXISTS, fmt.Sprintf("Tag %s already exists", tag.Name))
+		}
+		return model.Tag{}, util.MakeErrorUnknown(err)
+	}
+
+	return updated.ToTag(), nil
+}
+
+func (t Tag) DeleteTag(ctx context.Context, userId, id int64) error {
+	statement := t.DbConnection.NewSession(nil).DeleteBySql(`
+	DELETE FROM TAG WHERE id = ? AND user_id = ?
+	`, id, userId)
+
+	result, err := statement.ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return util.MakeError(util.NOT_FOUND, fmt.Sprintf("Tag %d not found", id))
+	}
+
+	return nil
+}
+
+func (t Tag) GetTagById(ctx context.Context, userId, id int64) (model.Tag, error) {
+	statement := t.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT * FROM TAG WHERE id = ? AND user_id = ?
+	`, id, userId)
+
+	var tag repositoryModel.Tag
+	err := statement.LoadOneContext(ctx, &tag)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return model.Tag{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("Tag %d not found", id))
+		}
+		return model.Tag{}, util.MakeErrorUnknown(err)
+	}
+
+	return tag.ToTag(), nil
+}
+
+func (t Tag) ListTags(ctx context.Context, userId int64) ([]model.Tag, error) {
+	statement := t.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT * FROM TAG WHERE user_id = ?
+	ORDER BY name
+	`, userId)
+
+	var tags []repositoryModel.Tag
+	_, err := statement.LoadContext(ctx, &tags)
+	if err != nil {
+		return []model.Tag{}, util.MakeErrorUnknown(err)
+	}
+
+	results := make([]model.Tag, len(tags))
+	for i, v := range tags {
+		results[i] = v.ToTag()
+	}
+
+	return results, nil
+}
+
+func (t Tag) AttachTag(ctx context.Context, purchaseId, tagId int64) error {
+	statement := t.DbConnection.NewSession(nil).InsertBySql(`
+	INSERT INTO TAG_PURCHASE(purchase_id, tag_id)
+		VALUES (?, ?)
+	ON CONFLICT DO NOTHING
+	`, purchaseId, tagId)
+
+	_, err := statement.ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
+
+func (t Tag) DetachTag(ctx context.Context, userId, purchaseId, tagId int64) error {
+	statement := t.DbConnection.NewSession(nil).DeleteBySql(`
+	DELETE FROM TAG_PURCHASE tp
+	USING TAG t
+	WHERE tp.tag_id = t.id AND t.user_id = ? AND tp.purchase_id = ? AND tp.tag_id = ?
+	`, userId, purchaseId, tagId)
+
+	_, err := statement.ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
@@ -5,14 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gocraft/dbr/v2"
+	"github.com/lib/pq"
 	"github.com/ronistone/market-list/src/model"
 	"github.com/ronistone/market-list/src/util"
+	"time"
 )
 
 type UserRepository interface {
 	CreateUser(ctx context.Context, purchase model.User) (model.User, error)
+	UpdateUser(ctx context.Context, user model.User) (model.User, error)
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	RehashPassword(ctx context.Context, id int64, password string) error
 	GetUserById(ctx context.Context, id int64) (model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (model.User, error)
 	GetUsersByPurchaseId(ctx context.Context, purchaseId int64) ([]model.User, error)
+	CreatePasswordResetToken(ctx context.Context, userId int64, tokenHash string, expiresAt time.Time) error
+	ResetPassword(ctx context.Context, tokenHash string, password string) (int64, error)
+	IsValidTimezone(ctx context.Context, timezone string) (bool, error)
 }
 
 type User struct {
@@ -25,21 +34,83 @@ func CreateUserRepository(connection *dbr.Connection) UserRepository {
 	}
 }
 
+// CreateUser also binds the pending invites sent to the email before the account existed.
 func (p User) CreateUser(ctx context.Context, user model.User) (model.User, error) {
 	statement := p.DbConnection.NewSession(nil).SelectBySql(`
-	INSERT INTO market_user(name, password) 
-		VALUES (?, ?) 
+	WITH created AS (
+		INSERT INTO market_user(email, name, password, timezone)
+			VALUES (?, ?, ?, ?)
+		RETURNING *
+	), bound AS (
+		UPDATE purchase_invite i SET invitee_id = c.id, updated_at = NOW()
+			FROM created c
+			WHERE i.invitee_id IS NULL AND i.status = ? AND LOWER(i.email) = LOWER(c.email)
+	)
+	SELECT * FROM created
+	`, user.Email, user.Name, user.Password, user.Timezone, model.INVITE_PENDING)
+
+	_, err := statement.LoadContext(ctx, &user)
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+			return model.User{}, util.MakeError(util.ALREADY_EXISTS, fmt.Sprintf("User %s already exists", user.Email))
+		}
+		return model.User{}, util.MakeErrorUnknown(err)
+	}
+
+	return user, nil
+}
+
+func (p User) UpdateUser(ctx context.Context, user model.User) (model.User, error) {
+	if user.Id == nil {
+		return model.User{}, util.MakeError(util.INVALID_INPUT, "invalid User Id")
+	}
+	statement := p.DbConnection.NewSession(nil).SelectBySql(`
+	UPDATE market_user SET email = ?, name = ?, timezone = ?, updated_at = NOW()
+		WHERE id = ?
 	RETURNING *
-	`, user.Name, user.Password)
+	`, user.Email, user.Name, user.Timezone, user.Id)
 
 	_, err := statement.LoadContext(ctx, &user)
 	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+			return model.User{}, util.MakeError(util.ALREADY_EXISTS, fmt.Sprintf("User %s already exists", user.Email))
+		}
 		return model.User{}, util.MakeErrorUnknown(err)
 	}
 
 	return user, nil
 }
 
+// UpdatePassword sets a new password and bumps the token version, revoking every token issued before.
+func (p User) UpdatePassword(ctx context.Context, id int64, password string) error {
+	statement := p.DbConnection.NewSession(nil).UpdateBySql(`
+	UPDATE market_user SET password = ?, token_version = token_version + 1, updated_at = NOW()
+		WHERE id = ?
+	`, password, id)
+
+	_, err := statement.ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
+
+// RehashPassword replaces the hash of the same password, so the issued tokens stay valid.
+func (p User) RehashPassword(ctx context.Context, id int64, password string) error {
+	statement := p.DbConnection.NewSession(nil).UpdateBySql(`
+	UPDATE market_user SET password = ?
+		WHERE id = ?
+	`, password, id)
+
+	_, err := statement.ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
+
 func (p User) GetUserById(ctx context.Context, id int64) (model.User, error) {
 	statement := p.DbConnection.NewSession(nil).SelectBySql(`
 	SELECT * FROM MARKET_USER where ID = ?
@@ -58,11 +129,30 @@ func (p User) GetUserById(ctx context.Context, id int64) (model.User, error) {
 	return user, nil
 }
 
+func (p User) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	statement := p.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT * FROM MARKET_USER where LOWER(EMAIL) = LOWER(?)
+	`, email)
+
+	var user model.User
+	err := statement.LoadOne(&user)
+
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return model.User{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("User %s not found", email))
+		}
+		return model.User{}, util.MakeErrorUnknown(err)
+	}
+
+	return user, nil
+}
+
 func (p User) GetUsersByPurchaseId(ctx context.Context, purchaseId int64) ([]model.User, error) {
 	statement := p.DbConnection.NewSession(nil).SelectBySql(`
-	SELECT * FROM MARKET_USER where ID IN (
-		SELECT USER_ID FROM PURCHASE_USER where PURCHASE_ID = ?
-	)
+	SELECT mu.*, pu.ROLE FROM MARKET_USER mu
+		INNER JOIN PURCHASE_USER pu ON pu.USER_ID = mu.ID
+	WHERE pu.PURCHASE_ID = ?
+	ORDER BY mu.ID
 	`, purchaseId)
 
 	var users []model.User
@@ -74,3 +164,70 @@ func (p User) GetUsersByPurchaseId(ctx context.Context, purchaseId int64) ([]mod
 
 	return users, nil
 }
+
+func (p User) CreatePasswordResetToken(ctx context.Context, userId int64, tokenHash string, expiresAt time.Time) error {
+	statement := p.DbConnection.NewSession(nil).InsertBySql(`
+	INSERT INTO password_reset_token(user_id, token_hash, expires_at)
+		VALUES (?, ?, ?)
+	`, userId, tokenHash, expiresAt)
+
+	_, err := statement.ExecContext(ctx)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return nil
+}
+
+// ResetPassword consumes the reset token and sets the password of its user in the same transaction, so the token is
+// only burnt when the password is changed. Like UpdatePassword it bumps the token version.
+func (p User) ResetPassword(ctx context.Context, tokenHash string, password string) (int64, error) {
+	tx, err := p.DbConnection.NewSession(nil).Begin()
+	if err != nil {
+		return 0, util.MakeErrorUnknown(err)
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	var userId int64
+	err = tx.SelectBySql(`
+	UPDATE password_reset_token SET used_at = NOW()
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > NOW()
+	RETURNING user_id
+	`, tokenHash).LoadOneContext(ctx, &userId)
+	if err != nil {
+		if errors.Is(err, dbr.ErrNotFound) {
+			return 0, util.MakeError(util.INVALID_INPUT, "invalid or expired reset token")
+		}
+		return 0, util.MakeErrorUnknown(err)
+	}
+
+	_, err = tx.UpdateBySql(`
+	UPDATE market_user SET password = ?, token_version = token_version + 1, updated_at = NOW()
+		WHERE id = ?
+	`, password, userId).ExecContext(ctx)
+	if err != nil {
+		return 0, util.MakeErrorUnknown(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, util.MakeErrorUnknown(err)
+	}
+
+	return userId, nil
+}
+
+// IsValidTimezone checks the timezone against the ones known by the database, which is where the reports apply it.
+func (p User) IsValidTimezone(ctx context.Context, timezone string) (bool, error) {
+	statement := p.DbConnection.NewSession(nil).SelectBySql(`
+	SELECT EXISTS (SELECT 1 FROM pg_timezone_names WHERE name = ?)
+	`, timezone)
+
+	var valid bool
+	err := statement.LoadOneContext(ctx, &valid)
+	if err != nil {
+		return false, util.MakeErrorUnknown(err)
+	}
+
+	return valid, nil
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"github.com/ronistone/market-list/src/model"
+	"time"
+)
+
+type InviteEntity struct {
+	Id           *int64     `db:"invite_id"`
+	Email        string     `db:"invite_email"`
+	Status       string     `db:"invite_status"`
+	Role         string     `db:"invite_role"`
+	CreatedAt    *time.Time `db:"invite_created_at"`
+	UpdatedAt    *time.Time `db:"invite_updated_at"`
+	PurchaseId   *int64     `db:"purchase_id"`
+	PurchaseName string     `db:"purchase_name"`
+	InviterId    *int64     `db:"inviter_id"`
+	InviterName  string     `db:"inviter_name"`
+	InviterEmail string     `db:"inviter_email"`
+}
+
+func (i InviteEntity) ToInvite() model.Invite {
+	return model.Invite{
+		Id: i.Id,
+		Purchase: model.Purchase{
+			Id:   i.PurchaseId,
+			Name: i.PurchaseName,
+		},
+		Inviter: model.User{
+			Id:    i.InviterId,
+			Name:  i.InviterName,
+			Email: i.InviterEmail,
+		},
+		Email:     i.Email,
+		Status:    model.InviteStatus(i.Status),
+		Role:      model.PurchaseRole(i.Role),
+		CreatedAt: i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+	}
+}
@@ -6,14 +6,19 @@ import (
 )
 
 type PurchaseEntity struct {
-	Id              *int64     `db:"purchase_id"`
-	Name            string     `db:"purchase_name"`
-	IsFavorite      bool       `db:"purchase_is_favorite"`
-	CreatedAt       *time.Time `db:"purchase_created_at"`
-	MarketId        *int64     `db:"_market_id"`
-	MarketName      *string    `db:"market_name"`
-	MarketCreatedAt *time.Time `db:"market_created_at"`
-	MarketUpdatedAt *time.Time `db:"market_updated_at"`
+	Id                 *int64     `db:"purchase_id"`
+	Name               string     `db:"purchase_name"`
+	IsFavorite         bool       `db:"purchase_is_favorite"`
+	CreatedAt          *time.Time `db:"purchase_created_at"`
+	MarketId           *int64     `db:"_market_id"`
+	MarketName         *string    `db:"market_name"`
+	MarketCreatedAt    *time.Time `db:"market_created_at"`
+	MarketUpdatedAt    *time.Time `db:"market_updated_at"`
+	TotalExpected      int64      `db:"purchase_total_expected"`
+	TotalSpent         int64      `db:"purchase_total_spent"`
+	ItemCount          int64      `db:"purchase_item_count"`
+	PurchasedItemCount int64      `db:"purchase_purchased_item_count"`
+	Role               string     `db:"purchase_role"`
 }
 
 func (p PurchaseEntity) ToPurchase() model.Purchase {
@@ -28,22 +33,33 @@ func (p PurchaseEntity) ToPurchase() model.Purchase {
 	}
 
 	return model.Purchase{
-		Id:         p.Id,
-		Name:       p.Name,
-		Market:     marketResult,
-		CreatedAt:  p.CreatedAt,
-		Items:      nil,
-		IsFavorite: p.IsFavorite,
-		MarketId:   p.MarketId,
+		Id:                 p.Id,
+		Name:               p.Name,
+		Market:             marketResult,
+		CreatedAt:          p.CreatedAt,
+		Items:              nil,
+		IsFavorite:         p.IsFavorite,
+		MarketId:           p.MarketId,
+		TotalExpected:      p.TotalExpected,
+		TotalSpent:         p.TotalSpent,
+		ItemCount:          p.ItemCount,
+		PurchasedItemCount: p.PurchasedItemCount,
+		Role:               model.PurchaseRole(p.Role),
 	}
 }
 
 type PurchaseItemProductInstance struct {
 	PurchaseItemId        *int64     `db:"purchase_item_id"`
+	PurchaseId            *int64     `db:"purchase_item_purchase_id"`
 	PurchaseItemPurchased bool       `db:"purchase_item_purchased"`
 	PurchaseItemQuantity  int        `db:"purchase_item_quantity"`
 	PurchaseItemCreatedAt *time.Time `db:"purchase_item_created_at"`
 	Price                 *int64     `db:"purchase_item_price"`
+	Version               int64      `db:"purchase_item_version"`
+	Notes                 *string    `db:"purchase_item_notes"`
+	Priority              int        `db:"purchase_item_priority"`
+	Optional              bool       `db:"purchase_item_optional"`
+	Position              int64      `db:"purchase_item_position"`
 	ProductId             *int64     `db:"prod_id"`
 	ProductName           string     `db:"prod_name"`
 	ProductEan            *string    `db:"prod_ean"`
@@ -51,24 +67,36 @@ type PurchaseItemProductInstance struct {
 	ProductSize           int64      `db:"prod_size"`
 	ProductCreatedAt      *time.Time `db:"prod_created_at"`
 	ProductUpdatedAt      *time.Time `db:"prod_updated_at"`
+	ProductVersion        int64      `db:"prod_version"`
+	ProductCategoryId     *int64     `db:"prod_category_id"`
 }
 
 func (p PurchaseItemProductInstance) ToPurchaseItem() model.PurchaseItem {
+	priority := p.Priority
+	optional := p.Optional
 	return model.PurchaseItem{
 		Id:       p.PurchaseItemId,
 		Purchase: nil,
 		Product: model.Product{
-			Id:        p.ProductId,
-			Ean:       p.ProductEan,
-			Name:      p.ProductName,
-			Unit:      p.ProductUnit,
-			Size:      p.ProductSize,
-			CreatedAt: p.ProductCreatedAt,
-			UpdatedAt: p.ProductUpdatedAt,
+			Id:         p.ProductId,
+			Ean:        p.ProductEan,
+			Name:       p.ProductName,
+			Unit:       p.ProductUnit,
+			Size:       p.ProductSize,
+			CreatedAt:  p.ProductCreatedAt,
+			UpdatedAt:  p.ProductUpdatedAt,
+			Version:    p.ProductVersion,
+			CategoryId: p.ProductCategoryId,
 		},
-		Price:     p.Price,
-		CreatedAt: p.PurchaseItemCreatedAt,
-		Purchased: p.PurchaseItemPurchased,
-		Quantity:  p.PurchaseItemQuantity,
+		Price:      p.Price,
+		CreatedAt:  p.PurchaseItemCreatedAt,
+		Purchased:  p.PurchaseItemPurchased,
+		Quantity:   p.PurchaseItemQuantity,
+		Version:    p.Version,
+		Notes:      p.Notes,
+		Priority:   &priority,
+		Optional:   &optional,
+		Position:   p.Position,
+		PurchaseId: p.PurchaseId,
 	}
 }
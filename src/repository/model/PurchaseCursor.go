@@ -0,0 +1,84 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+	"strconv"
+	"time"
+)
+
+// PurchaseCursor is the keyset position of the last purchase returned by a list page.
+type PurchaseCursor struct {
+	Sort       model.PurchaseSort `json:"s"`
+	Descending bool               `json:"d"`
+	Value      string             `json:"v"`
+	Id         int64              `json:"i"`
+}
+
+func CreatePurchaseCursor(filter model.PurchaseFilter, purchase PurchaseEntity) PurchaseCursor {
+	cursor := PurchaseCursor{
+		Sort:       filter.Sort,
+		Descending: filter.Descending,
+		Id:         *purchase.Id,
+	}
+
+	switch filter.Sort {
+	case model.PURCHASE_SORT_NAME:
+		cursor.Value = purchase.Name
+	case model.PURCHASE_SORT_TOTAL:
+		cursor.Value = strconv.FormatInt(purchase.TotalExpected, 10)
+	default:
+		if purchase.CreatedAt != nil {
+			cursor.Value = purchase.CreatedAt.UTC().Format(time.RFC3339Nano)
+		}
+	}
+
+	return cursor
+}
+
+func (c PurchaseCursor) Encode() string {
+	value, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+func DecodePurchaseCursor(encoded string, filter model.PurchaseFilter) (PurchaseCursor, error) {
+	invalidCursor := util.MakeError(util.INVALID_INPUT, "invalid cursor")
+
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return PurchaseCursor{}, invalidCursor
+	}
+
+	var cursor PurchaseCursor
+	if err = json.Unmarshal(value, &cursor); err != nil {
+		return PurchaseCursor{}, invalidCursor
+	}
+
+	if cursor.Sort != filter.Sort || cursor.Descending != filter.Descending {
+		return PurchaseCursor{}, util.MakeError(util.INVALID_INPUT, "cursor does not match the requested sort")
+	}
+
+	return cursor, nil
+}
+
+// SortValue converts the cursor value back to the type of the sorted column.
+func (c PurchaseCursor) SortValue() (interface{}, error) {
+	switch c.Sort {
+	case model.PURCHASE_SORT_NAME:
+		return c.Value, nil
+	case model.PURCHASE_SORT_TOTAL:
+		total, err := strconv.ParseInt(c.Value, 10, 64)
+		if err != nil {
+			return nil, util.MakeError(util.INVALID_INPUT, "invalid cursor")
+		}
+		return total, nil
+	default:
+		createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, util.MakeError(util.INVALID_INPUT, "invalid cursor")
+		}
+		return createdAt, nil
+	}
+}
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"github.com/ronistone/market-list/src/model"
+	"time"
+)
 
 type Tag struct {
 	Id        *int64     `db:"id"`
@@ -9,7 +12,37 @@ type Tag struct {
 	CreatedAt *time.Time `db:"created_at"`
 }
 
+func (t Tag) ToTag() model.Tag {
+	userId := t.UserId
+	tag := model.Tag{
+		Name:      t.Name,
+		User:      model.User{Id: &userId},
+		CreatedAt: t.CreatedAt,
+	}
+	if t.Id != nil {
+		tag.Id = *t.Id
+	}
+	return tag
+}
+
 type TagPurchase struct {
 	PurchaseId int64 `db:"purchase_id"`
-	UserId     int64 `db:"user_id"`
+	TagId      int64 `db:"tag_id"`
+}
+
+type PurchaseTagInstance struct {
+	PurchaseId   int64      `db:"purchase_id"`
+	TagId        *int64     `db:"tag_id"`
+	TagName      string     `db:"tag_name"`
+	TagUserId    int64      `db:"tag_user_id"`
+	TagCreatedAt *time.Time `db:"tag_created_at"`
+}
+
+func (p PurchaseTagInstance) ToTag() model.Tag {
+	return Tag{
+		Id:        p.TagId,
+		Name:      p.TagName,
+		UserId:    p.TagUserId,
+		CreatedAt: p.TagCreatedAt,
+	}.ToTag()
 }
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+)
+
+// AlertNotifier delivers a price alert to its user, on top of the alert inbox.
+type AlertNotifier interface {
+	NotifyPriceAlert(ctx context.Context, alert model.PriceAlert) error
+}
+
+// LogAlertNotifier writes the alert to the request logger, useful for local development.
+type LogAlertNotifier struct{}
+
+func CreateLogAlertNotifier() AlertNotifier {
+	return &LogAlertNotifier{}
+}
+
+func (l LogAlertNotifier) NotifyPriceAlert(ctx context.Context, alert model.PriceAlert) error {
+	util.Logger(ctx).Infof("Price alert %s for user (%v): product (%v) at %d, reference %d",
+		alert.Type, alert.UserId, alert.ProductId, alert.Price, alert.ReferencePrice)
+	return nil
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/repository"
+	"github.com/ronistone/market-list/src/util"
+	"math"
+	"time"
+)
+
+// ALERT_TRAILING_DAYS is how far back the prices averaged to detect a price spike go.
+const ALERT_TRAILING_DAYS = 90
+
+type AlertService interface {
+	SaveWatch(ctx context.Context, watch model.ProductWatch) (model.ProductWatch, error)
+	UpdateWatch(ctx context.Context, watch model.ProductWatch) (model.ProductWatch, error)
+	DeleteWatch(ctx context.Context, id int64) error
+	ListWatches(ctx context.Context) ([]model.ProductWatch, error)
+	ListAlerts(ctx context.Context, unreadOnly bool) ([]model.PriceAlert, error)
+	MarkAlertRead(ctx context.Context, id int64) (model.PriceAlert, error)
+	EvaluatePrice(ctx context.Context, item model.PurchaseItem) error
+}
+
+type Alert struct {
+	AlertRepository    repository.AlertRepository
+	PriceRepository    repository.PriceRepository
+	PurchaseRepository repository.PurchaseRepository
+	ProductService     ProductService
+	AlertNotifier      AlertNotifier
+}
+
+func CreateAlertService(
+	alertRepository repository.AlertRepository,
+	priceRepository repository.PriceRepository,
+	purchaseRepository repository.PurchaseRepository,
+	productService ProductService,
+	alertNotifier AlertNotifier,
+) AlertService {
+	return &Alert{
+		AlertRepository:    alertRepository,
+		PriceRepository:    priceRepository,
+		PurchaseRepository: purchaseRepository,
+		ProductService:     productService,
+		AlertNotifier:      alertNotifier,
+	}
+}
+
+// SaveWatch watches the product given by id or by EAN, replacing the thresholds of an existing watch on it.
+func (a Alert) SaveWatch(ctx context.Context, watch model.ProductWatch) (model.ProductWatch, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.ProductWatch{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	err := validateWatch(watch)
+	if err != nil {
+		return model.ProductWatch{}, err
+	}
+
+	var product model.Product
+	switch {
+	case watch.ProductId != nil:
+		product, err = a.ProductService.GetById(ctx, *watch.ProductId)
+	case watch.Ean != nil && len(*watch.Ean) > 0:
+		product, err = a.ProductService.GetByEan(ctx, *watch.Ean)
+	default:
+		return model.ProductWatch{}, util.MakeError(util.INVALID_INPUT, "productId or ean is required")
+	}
+	if err != nil {
+		return model.ProductWatch{}, err
+	}
+
+	watch.UserId = *userId
+	watch.ProductId = product.Id
+	return a.AlertRepository.SaveWatch(ctx, watch)
+}
+
+func (a Alert) UpdateWatch(ctx context.Context, watch model.ProductWatch) (model.ProductWatch, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.ProductWatch{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	err := validateWatch(watch)
+	if err != nil {
+		return model.ProductWatch{}, err
+	}
+
+	watch.UserId = *userId
+	return a.AlertRepository.UpdateWatch(ctx, watch)
+}
+
+func validateWatch(watch model.ProductWatch) error {
+	if watch.PriceBelow == nil && watch.SpikePercent == nil {
+		return util.MakeError(util.INVALID_INPUT, "priceBelow or spikePercent is required")
+	}
+	if watch.PriceBelow != nil && *watch.PriceBelow <= 0 {
+		return util.MakeError(util.INVALID_INPUT, "priceBelow must be positive")
+	}
+	if watch.SpikePercent != nil && *watch.SpikePercent <= 0 {
+		return util.MakeError(util.INVALID_INPUT, "spikePercent must be positive")
+	}
+	return nil
+}
+
+func (a Alert) DeleteWatch(ctx context.Context, id int64) error {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return a.AlertRepository.DeleteWatch(ctx, *userId, id)
+}
+
+func (a Alert) ListWatches(ctx context.Context) ([]model.ProductWatch, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return nil, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return a.AlertRepository.ListWatches(ctx, *userId)
+}
+
+func (a Alert) ListAlerts(ctx context.Context, unreadOnly bool) ([]model.PriceAlert, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return nil, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return a.AlertRepository.ListAlerts(ctx, *userId, unreadOnly)
+}
+
+func (a Alert) MarkAlertRead(ctx context.Context, id int64) (model.PriceAlert, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.PriceAlert{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return a.AlertRepository.MarkAlertRead(ctx, *userId, id)
+}
+
+// EvaluatePrice checks the price just recorded for the item against the watches of the participants of its purchase.
+// Spikes are measured against what the watching user paid at the market of the purchase in the trailing days.
+func (a Alert) EvaluatePrice(ctx context.Context, item model.PurchaseItem) error {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	if item.Price == nil || item.Product.Id == nil || item.PurchaseId == nil {
+		return nil
+	}
+
+	watches, err := a.AlertRepository.ListPurchaseWatches(ctx, *item.PurchaseId, *item.Product.Id)
+	if err != nil || len(watches) == 0 {
+		return err
+	}
+
+	purchase, err := a.PurchaseRepository.GetPurchaseById(ctx, *userId, *item.PurchaseId)
+	if err != nil {
+		return err
+	}
+	since := time.Now().AddDate(0, 0, -ALERT_TRAILING_DAYS)
+
+	for _, watch := range watches {
+		alert := model.PriceAlert{
+			UserId:         watch.UserId,
+			WatchId:        *watch.Id,
+			ProductId:      *item.Product.Id,
+			MarketId:       purchase.MarketId,
+			PurchaseId:     item.PurchaseId,
+			PurchaseItemId: item.Id,
+			Price:          *item.Price,
+		}
+
+		if watch.PriceBelow != nil && *item.Price < *watch.PriceBelow {
+			alert.Type = model.PRICE_ALERT_DROP
+			alert.ReferencePrice = *watch.PriceBelow
+			a.raiseAlert(ctx, alert)
+		}
+
+		if watch.SpikePercent != nil {
+			average, err := a.PriceRepository.GetAveragePrice(ctx, watch.UserId, *item.Product.Id, purchase.MarketId, since, *item.Id)
+			if err != nil {
+				return err
+			}
+			if average != nil && float64(*item.Price) > *average*(1+float64(*watch.SpikePercent)/100) {
+				alert.Type = model.PRICE_ALERT_SPIKE
+				alert.ReferencePrice = int64(math.Round(*average))
+				a.raiseAlert(ctx, alert)
+			}
+		}
+	}
+
+	return nil
+}
+
+// raiseAlert stores the alert and hands it to the notifier. Failures are only logged, the inbox being best effort.
+func (a Alert) raiseAlert(ctx context.Context, alert model.PriceAlert) {
+	created, err := a.AlertRepository.CreateAlert(ctx, alert)
+	if err != nil {
+		util.Logger(ctx).Warnf("Failed to save %s alert of watch (%v): %v", alert.Type, alert.WatchId, err)
+		return
+	}
+	if created == nil {
+		return
+	}
+
+	err = a.AlertNotifier.NotifyPriceAlert(ctx, *created)
+	if err != nil {
+		util.Logger(ctx).Warnf("Failed to notify alert (%v) to user (%v): %v", *created.Id, created.UserId, err)
+	}
+}
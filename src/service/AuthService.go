@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+	"strconv"
+	"time"
+)
+
+type AuthService interface {
+	SignUp(ctx context.Context, user model.User) (model.Token, error)
+	Login(ctx context.Context, credentials model.Credentials) (model.Token, error)
+	Authenticate(ctx context.Context, token string) (int64, error)
+}
+
+type Auth struct {
+	UserService     UserService
+	Secret          []byte
+	TokenExpiration time.Duration
+}
+
+// tokenClaims stamps the token version of the user, so changing the password revokes the tokens issued before.
+type tokenClaims struct {
+	jwt.StandardClaims
+	Version int64 `json:"ver"`
+}
+
+// AUTH_SECRET_MIN_LENGTH is the minimum length in bytes of the secret the tokens are signed with.
+const AUTH_SECRET_MIN_LENGTH = 32
+
+// insecureAuthSecrets are secrets that were published with the project and must never sign tokens.
+var insecureAuthSecrets = []string{"market-list-secret"}
+
+func CreateAuthService(userService UserService, secret string, tokenExpiration time.Duration) (AuthService, error) {
+	for _, insecure := range insecureAuthSecrets {
+		if secret == insecure {
+			return nil, errors.New("auth secret is the published default, set MARKETLIST_AUTH_SECRET")
+		}
+	}
+	if len(secret) < AUTH_SECRET_MIN_LENGTH {
+		return nil, fmt.Errorf("auth secret must have at least %d bytes, set MARKETLIST_AUTH_SECRET", AUTH_SECRET_MIN_LENGTH)
+	}
+
+	return &Auth{
+		UserService:     userService,
+		Secret:          []byte(secret),
+		TokenExpiration: tokenExpiration,
+	}, nil
+}
+
+func (a Auth) SignUp(ctx context.Context, user model.User) (model.Token, error) {
+	created, err := a.UserService.CreateUser(ctx, user)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	return a.generateToken(created)
+}
+
+func (a Auth) Login(ctx context.Context, credentials model.Credentials) (model.Token, error) {
+	user, err := a.UserService.CheckCredentials(ctx, credentials)
+	if err != nil {
+		return model.Token{}, err
+	}
+
+	return a.generateToken(user)
+}
+
+func (a Auth) Authenticate(ctx context.Context, token string) (int64, error) {
+	claims := tokenClaims{}
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return a.Secret, nil
+	})
+	if err != nil {
+		return 0, util.MakeError(util.UNAUTHORIZED, "invalid token")
+	}
+
+	userId, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, util.MakeError(util.UNAUTHORIZED, "invalid token")
+	}
+
+	user, err := a.UserService.GetUser(ctx, userId)
+	if err != nil {
+		var mkError *util.MarketListError
+		if errors.As(err, &mkError) && mkError.ErrorType == util.NOT_FOUND {
+			return 0, util.MakeError(util.UNAUTHORIZED, "invalid token")
+		}
+		return 0, err
+	}
+	if user.TokenVersion != claims.Version {
+		return 0, util.MakeError(util.UNAUTHORIZED, "token revoked")
+	}
+
+	return userId, nil
+}
+
+func (a Auth) generateToken(user model.User) (model.Token, error) {
+	now := time.Now()
+	expiresAt := now.Add(a.TokenExpiration)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.FormatInt(*user.Id, 10),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+		Version: user.TokenVersion,
+	})
+
+	signed, err := token.SignedString(a.Secret)
+	if err != nil {
+		return model.Token{}, util.MakeErrorUnknown(err)
+	}
+
+	return model.Token{
+		Token:     signed,
+		ExpiresAt: expiresAt,
+	}, nil
+}
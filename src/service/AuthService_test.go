@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAuthSecret = "0123456789abcdef0123456789abcdef"
+
+// authUserService only knows the users of its map.
+type authUserService struct {
+	UserService
+	users map[int64]model.User
+}
+
+func (a authUserService) GetUser(ctx context.Context, id int64) (model.User, error) {
+	user, ok := a.users[id]
+	if !ok {
+		return model.User{}, util.MakeError(util.NOT_FOUND, "User not found")
+	}
+	return user, nil
+}
+
+func TestCreateAuthService(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		valid  bool
+	}{
+		{name: "long secret", secret: testAuthSecret, valid: true},
+		{name: "published secret", secret: "market-list-secret"},
+		{name: "short secret", secret: testAuthSecret[:AUTH_SECRET_MIN_LENGTH-1]},
+		{name: "empty secret", secret: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := CreateAuthService(authUserService{}, test.secret, time.Hour)
+			if (err == nil) != test.valid {
+				t.Errorf("err = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	userId := int64(7)
+	users := authUserService{users: map[int64]model.User{userId: {Id: &userId, TokenVersion: 2}}}
+	authService, err := CreateAuthService(users, testAuthSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := authService.(*Auth)
+
+	sign := func(method jwt.SigningMethod, key interface{}, claims tokenClaims) string {
+		signed, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	claims := func(subject string, version int64, expiresAt time.Time) tokenClaims {
+		return tokenClaims{
+			StandardClaims: jwt.StandardClaims{Subject: subject, ExpiresAt: expiresAt.Unix()},
+			Version:        version,
+		}
+	}
+	current, err := auth.generateToken(users.users[userId])
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := current.Token
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "issued token", token: valid, valid: true},
+		{name: "token before a password change", token: sign(jwt.SigningMethodHS256, auth.Secret, claims("7", 1, later))},
+		{name: "expired token", token: sign(jwt.SigningMethodHS256, auth.Secret, claims("7", 2, time.Now().Add(-time.Minute)))},
+		{name: "other secret", token: sign(jwt.SigningMethodHS256, []byte(strings.Repeat("x", 32)), claims("7", 2, later))},
+		{name: "unsigned token", token: sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims("7", 2, later))},
+		{name: "unknown user", token: sign(jwt.SigningMethodHS256, auth.Secret, claims("8", 0, later))},
+		{name: "invalid subject", token: sign(jwt.SigningMethodHS256, auth.Secret, claims("seven", 2, later))},
+		{name: "malformed token", token: "not a token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authenticated, err := authService.Authenticate(context.Background(), test.token)
+			if !test.valid {
+				var mkError *util.MarketListError
+				if !errors.As(err, &mkError) || mkError.ErrorType != util.UNAUTHORIZED {
+					t.Fatalf("err = %v, want UNAUTHORIZED", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if authenticated != userId {
+				t.Errorf("user = %d, want %d", authenticated, userId)
+			}
+		})
+	}
+}
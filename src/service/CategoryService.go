@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/repository"
+	"github.com/ronistone/market-list/src/util"
+	"strings"
+)
+
+type CategoryService interface {
+	Create(ctx context.Context, category model.Category) (model.Category, error)
+	Update(ctx context.Context, category model.Category) (model.Category, error)
+	Delete(ctx context.Context, id int64) error
+	GetById(ctx context.Context, id int64) (model.Category, error)
+	List(ctx context.Context) ([]model.Category, error)
+	Suggest(ctx context.Context, productName string) (model.Category, error)
+	SuggestForPurchase(ctx context.Context, purchaseId int64) (map[int64]int64, error)
+}
+
+// Category manages the taxonomy shared by every user, so only administrators change it.
+type Category struct {
+	CategoryRepository repository.CategoryRepository
+	UserService        UserService
+}
+
+func CreateCategoryService(categoryRepository repository.CategoryRepository, userService UserService) CategoryService {
+	return &Category{
+		CategoryRepository: categoryRepository,
+		UserService:        userService,
+	}
+}
+
+func (c Category) Create(ctx context.Context, category model.Category) (model.Category, error) {
+	if err := c.UserService.CheckAdmin(ctx, "create categories"); err != nil {
+		return model.Category{}, err
+	}
+	category.Name = strings.TrimSpace(category.Name)
+	if len(category.Name) == 0 {
+		return model.Category{}, util.MakeError(util.INVALID_INPUT, "Category name cannot be empty")
+	}
+	return c.CategoryRepository.CreateCategory(ctx, category)
+}
+
+func (c Category) Update(ctx context.Context, category model.Category) (model.Category, error) {
+	if err := c.UserService.CheckAdmin(ctx, "update categories"); err != nil {
+		return model.Category{}, err
+	}
+	category.Name = strings.TrimSpace(category.Name)
+	if len(category.Name) == 0 {
+		return model.Category{}, util.MakeError(util.INVALID_INPUT, "Category name cannot be empty")
+	}
+	return c.CategoryRepository.UpdateCategory(ctx, category)
+}
+
+func (c Category) Delete(ctx context.Context, id int64) error {
+	if err := c.UserService.CheckAdmin(ctx, "delete categories"); err != nil {
+		return err
+	}
+	return c.CategoryRepository.DeleteCategory(ctx, id)
+}
+
+func (c Category) GetById(ctx context.Context, id int64) (model.Category, error) {
+	return c.CategoryRepository.GetCategoryById(ctx, id)
+}
+
+func (c Category) List(ctx context.Context) ([]model.Category, error) {
+	return c.CategoryRepository.ListCategories(ctx)
+}
+
+func (c Category) Suggest(ctx context.Context, productName string) (model.Category, error) {
+	productName = strings.TrimSpace(productName)
+	if len(productName) == 0 {
+		return model.Category{}, util.MakeError(util.INVALID_INPUT, "name is required")
+	}
+	return c.CategoryRepository.SuggestCategory(ctx, productName)
+}
+
+func (c Category) SuggestForPurchase(ctx context.Context, purchaseId int64) (map[int64]int64, error) {
+	return c.CategoryRepository.SuggestCategoriesForPurchase(ctx, purchaseId)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/repository"
+	"github.com/ronistone/market-list/src/util"
+)
+
+type InviteService interface {
+	Create(ctx context.Context, purchaseId int64, email string, role model.PurchaseRole) (model.Invite, error)
+	ListPending(ctx context.Context) ([]model.Invite, error)
+	Accept(ctx context.Context, id int64) (model.Purchase, error)
+	Decline(ctx context.Context, id int64) error
+}
+
+type Invite struct {
+	InviteRepository repository.InviteRepository
+	PurchaseService  PurchaseService
+	UserService      UserService
+}
+
+func CreateInviteService(
+	inviteRepository repository.InviteRepository,
+	purchaseService PurchaseService,
+	userService UserService,
+) InviteService {
+	return &Invite{
+		InviteRepository: inviteRepository,
+		PurchaseService:  purchaseService,
+		UserService:      userService,
+	}
+}
+
+func (i Invite) Create(ctx context.Context, purchaseId int64, email string, role model.PurchaseRole) (model.Invite, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.Invite{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return model.Invite{}, err
+	}
+
+	if len(role) == 0 {
+		role = model.PURCHASE_ROLE_EDITOR
+	}
+	if !role.IsValid() {
+		return model.Invite{}, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("invalid role %s", role))
+	}
+
+	purchase, err := i.PurchaseService.GetPurchase(ctx, purchaseId)
+	if err != nil {
+		return model.Invite{}, err
+	}
+
+	if purchase.Role != model.PURCHASE_ROLE_OWNER {
+		return model.Invite{}, util.MakeError(util.FORBIDDEN, fmt.Sprintf("%s participants are not allowed to manage participants", purchase.Role))
+	}
+
+	inviteeId, err := i.findInvitee(ctx, email)
+	if err != nil {
+		return model.Invite{}, err
+	}
+
+	for _, user := range purchase.Users {
+		if inviteeId != nil && *user.Id == *inviteeId {
+			return model.Invite{}, util.MakeError(util.ALREADY_EXISTS, fmt.Sprintf("%s already participates in the purchase", email))
+		}
+	}
+
+	invite, err := i.InviteRepository.CreateInvite(ctx, purchaseId, *userId, inviteeId, email, role)
+	if err != nil {
+		return model.Invite{}, err
+	}
+
+	util.Logger(ctx).Infof("User (%v) invited %s to purchase (%v)", *userId, email, purchaseId)
+
+	return invite, nil
+}
+
+// findInvitee returns the id of the account with the email, or nil when nobody signed up with it yet. Both cases
+// create the same invite, so inviting does not tell whether an email has an account.
+func (i Invite) findInvitee(ctx context.Context, email string) (*int64, error) {
+	invitee, err := i.UserService.GetUserByEmail(ctx, email)
+	if err != nil {
+		var mkError *util.MarketListError
+		if errors.As(err, &mkError) && mkError.ErrorType == util.NOT_FOUND {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return invitee.Id, nil
+}
+
+func (i Invite) ListPending(ctx context.Context) ([]model.Invite, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return []model.Invite{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return i.InviteRepository.ListPendingInvites(ctx, *userId)
+}
+
+func (i Invite) Accept(ctx context.Context, id int64) (model.Purchase, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+
+	purchaseId, err := i.InviteRepository.AcceptInvite(ctx, id, *userId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	util.Logger(ctx).Infof("User (%v) accepted invite (%v) to purchase (%v)", *userId, id, purchaseId)
+
+	return i.PurchaseService.GetPurchase(ctx, purchaseId)
+}
+
+func (i Invite) Decline(ctx context.Context, id int64) error {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return i.InviteRepository.DeclineInvite(ctx, id, *userId)
+}
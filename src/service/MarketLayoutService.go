@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/repository"
+	"github.com/ronistone/market-list/src/util"
+	"math"
+	"sort"
+	"strings"
+)
+
+type MarketLayoutService interface {
+	GetLayout(ctx context.Context, marketId int64) (model.MarketLayout, error)
+	SaveLayout(ctx context.Context, layout model.MarketLayout) (model.MarketLayout, error)
+	SortItems(ctx context.Context, marketId int64, items []model.PurchaseItem) ([]model.PurchaseItem, error)
+}
+
+type MarketLayout struct {
+	MarketLayoutRepository repository.MarketLayoutRepository
+	MarketService          MarketService
+	UserService            UserService
+}
+
+func CreateMarketLayoutService(
+	marketLayoutRepository repository.MarketLayoutRepository,
+	marketService MarketService,
+	userService UserService,
+) MarketLayoutService {
+	return &MarketLayout{
+		MarketLayoutRepository: marketLayoutRepository,
+		MarketService:          marketService,
+		UserService:            userService,
+	}
+}
+
+func (m MarketLayout) GetLayout(ctx context.Context, marketId int64) (model.MarketLayout, error) {
+	_, err := m.MarketService.GetById(ctx, marketId)
+	if err != nil {
+		return model.MarketLayout{}, err
+	}
+	return m.MarketLayoutRepository.GetMarketLayout(ctx, marketId)
+}
+
+// SaveLayout replaces the layout of the market. Aisles are walked in the order they are given. Markets are shared by
+// every user, so only administrators change their layouts.
+func (m MarketLayout) SaveLayout(ctx context.Context, layout model.MarketLayout) (model.MarketLayout, error) {
+	err := m.UserService.CheckAdmin(ctx, "change market layouts")
+	if err != nil {
+		return model.MarketLayout{}, err
+	}
+	_, err = m.MarketService.GetById(ctx, layout.MarketId)
+	if err != nil {
+		return model.MarketLayout{}, err
+	}
+
+	categories := make(map[int64]bool)
+	products := make(map[int64]bool)
+	for i := range layout.Aisles {
+		aisle := &layout.Aisles[i]
+		aisle.Name = strings.TrimSpace(aisle.Name)
+		if len(aisle.Name) == 0 {
+			return model.MarketLayout{}, util.MakeError(util.INVALID_INPUT, "Aisle name cannot be empty")
+		}
+		aisle.Position = i
+		for _, categoryId := range aisle.CategoryIds {
+			if categories[categoryId] {
+				return model.MarketLayout{}, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("Category %d is in more than one aisle", categoryId))
+			}
+			categories[categoryId] = true
+		}
+		for _, productId := range aisle.ProductIds {
+			if products[productId] {
+				return model.MarketLayout{}, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("Product %d is in more than one aisle", productId))
+			}
+			products[productId] = true
+		}
+	}
+
+	err = m.MarketLayoutRepository.SaveMarketLayout(ctx, layout)
+	if err != nil {
+		return model.MarketLayout{}, err
+	}
+
+	return m.MarketLayoutRepository.GetMarketLayout(ctx, layout.MarketId)
+}
+
+type walkingOrderKey struct {
+	aisle    int
+	position float64
+	learned  bool
+}
+
+// SortItems puts the items in the order they are found walking through the market. Items go to the aisle of their
+// product, or else of its category, or else to the aisle their product is usually checked off around. Within an aisle,
+// and for markets without a layout, items follow the order they were checked off in past purchases at the market.
+// Items nothing is known about keep their relative order after the rest, and purchased items stay last.
+func (m MarketLayout) SortItems(ctx context.Context, marketId int64, items []model.PurchaseItem) ([]model.PurchaseItem, error) {
+	if len(items) < 2 {
+		return items, nil
+	}
+
+	layout, err := m.MarketLayoutRepository.GetMarketLayout(ctx, marketId)
+	if err != nil {
+		return nil, err
+	}
+	checkOrder, err := m.MarketLayoutRepository.GetMarketCheckOrder(ctx, marketId)
+	if err != nil {
+		return nil, err
+	}
+	if len(layout.Aisles) == 0 && len(checkOrder.Products) == 0 {
+		return items, nil
+	}
+
+	productAisles := make(map[int64]int)
+	categoryAisles := make(map[int64]int)
+	for i, aisle := range layout.Aisles {
+		for _, productId := range aisle.ProductIds {
+			productAisles[productId] = i
+		}
+		for _, categoryId := range aisle.CategoryIds {
+			categoryAisles[categoryId] = i
+		}
+	}
+
+	keys := make([]walkingOrderKey, len(items))
+	for i := range items {
+		item := &items[i]
+		var key walkingOrderKey
+		var mapped bool
+		if item.Product.Id != nil {
+			key.position, key.learned = checkOrder.Products[*item.Product.Id]
+			key.aisle, mapped = productAisles[*item.Product.Id]
+		}
+		if !mapped && item.Product.CategoryId != nil {
+			key.aisle, mapped = categoryAisles[*item.Product.CategoryId]
+		}
+		if !mapped {
+			key.aisle = len(layout.Aisles)
+			if key.learned {
+				key.aisle = nearestAisle(layout.Aisles, checkOrder.Aisles, key.position)
+			}
+		}
+		if key.aisle < len(layout.Aisles) {
+			item.AisleId = layout.Aisles[key.aisle].Id
+		}
+		keys[i] = key
+	}
+
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		itemA, itemB := items[indexes[a]], items[indexes[b]]
+		if itemA.Purchased != itemB.Purchased {
+			return !itemA.Purchased
+		}
+		keyA, keyB := keys[indexes[a]], keys[indexes[b]]
+		if keyA.aisle != keyB.aisle {
+			return keyA.aisle < keyB.aisle
+		}
+		if keyA.learned != keyB.learned {
+			return keyA.learned
+		}
+		return keyA.position < keyB.position
+	})
+
+	sorted := make([]model.PurchaseItem, len(items))
+	for i, index := range indexes {
+		sorted[i] = items[index]
+	}
+
+	return sorted, nil
+}
+
+// nearestAisle returns the aisle usually walked by closest to position, or len(aisles) when no aisle was learned yet.
+func nearestAisle(aisles []model.MarketAisle, aislePositions map[int64]float64, position float64) int {
+	nearest := len(aisles)
+	distance := math.Inf(1)
+	for i, aisle := range aisles {
+		aislePosition, ok := aislePositions[*aisle.Id]
+		if ok && math.Abs(aislePosition-position) < distance {
+			nearest = i
+			distance = math.Abs(aislePosition - position)
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"github.com/ronistone/market-list/src/model"
+	"sort"
+)
+
+type MarketRecommendationService interface {
+	Recommend(ctx context.Context, purchaseId int64, split bool) (model.MarketRecommendation, error)
+}
+
+type MarketRecommendation struct {
+	PurchaseService PurchaseService
+	PriceService    PriceService
+	MarketService   MarketService
+}
+
+func CreateMarketRecommendationService(
+	purchaseService PurchaseService,
+	priceService PriceService,
+	marketService MarketService,
+) MarketRecommendationService {
+	return &MarketRecommendation{
+		PurchaseService: purchaseService,
+		PriceService:    priceService,
+		MarketService:   marketService,
+	}
+}
+
+type productMarket struct {
+	productId int64
+	marketId  int64
+}
+
+// Recommend estimates the cost of the items still to buy at every enabled market. With split, it also looks for
+// the pair of markets that prices the most items for the lowest total, when it beats every single market.
+func (m MarketRecommendation) Recommend(ctx context.Context, purchaseId int64, split bool) (model.MarketRecommendation, error) {
+	purchase, err := m.PurchaseService.GetPurchaseSorted(ctx, purchaseId, model.PURCHASE_ITEM_SORT_DEFAULT)
+	if err != nil {
+		return model.MarketRecommendation{}, err
+	}
+
+	var items []model.PurchaseItem
+	var productIds []int64
+	for _, item := range purchase.Items {
+		if !item.Purchased && item.Product.Id != nil {
+			items = append(items, item)
+			productIds = append(productIds, *item.Product.Id)
+		}
+	}
+
+	markets, err := m.MarketService.List(ctx)
+	if err != nil {
+		return model.MarketRecommendation{}, err
+	}
+	observations, err := m.PriceService.GetLatestPrices(ctx, productIds)
+	if err != nil {
+		return model.MarketRecommendation{}, err
+	}
+	prices := make(map[productMarket]model.PriceObservation, len(observations))
+	for _, observation := range observations {
+		prices[productMarket{observation.ProductId, observation.MarketId}] = observation
+	}
+
+	recommendation := model.MarketRecommendation{
+		PurchaseId: purchaseId,
+		Markets:    make([]model.MarketEstimate, len(markets)),
+	}
+	for i, market := range markets {
+		recommendation.Markets[i] = estimateAtMarket(market, items, prices)
+	}
+	sort.SliceStable(recommendation.Markets, func(a, b int) bool {
+		return betterEstimate(recommendation.Markets[a], recommendation.Markets[b])
+	})
+
+	if len(recommendation.Markets) > 0 && len(recommendation.Markets[0].Items) > 0 {
+		recommendation.CheapestMarketId = recommendation.Markets[0].Market.Id
+	}
+	if split && recommendation.CheapestMarketId != nil {
+		recommendation.Split = bestSplit(recommendation.Markets, items)
+	}
+
+	return recommendation, nil
+}
+
+func estimateAtMarket(market model.Market, items []model.PurchaseItem, prices map[productMarket]model.PriceObservation) model.MarketEstimate {
+	estimate := model.MarketEstimate{
+		Market:         market,
+		Items:          []model.MarketItemEstimate{},
+		MissingItemIds: []int64{},
+	}
+	for _, item := range items {
+		observation, ok := prices[productMarket{*item.Product.Id, *market.Id}]
+		if !ok {
+			estimate.MissingItemIds = append(estimate.MissingItemIds, *item.Id)
+			continue
+		}
+		itemEstimate := model.MarketItemEstimate{
+			ItemId:     *item.Id,
+			ProductId:  *item.Product.Id,
+			Quantity:   item.Quantity,
+			UnitPrice:  observation.Price,
+			Total:      observation.Price * int64(item.Quantity),
+			ObservedAt: observation.ObservedAt,
+		}
+		estimate.Items = append(estimate.Items, itemEstimate)
+		estimate.Total += itemEstimate.Total
+	}
+	return estimate
+}
+
+// betterEstimate prefers the estimate pricing more items, and then the cheaper one.
+func betterEstimate(a, b model.MarketEstimate) bool {
+	if len(a.Items) != len(b.Items) {
+		return len(a.Items) > len(b.Items)
+	}
+	return a.Total < b.Total
+}
+
+// bestSplit tries every pair of markets, buying each item where it is cheaper. It returns nil when no pair prices more
+// items than the cheapest market or the same items for less, or when the best pair buys everything at one market.
+func bestSplit(estimates []model.MarketEstimate, items []model.PurchaseItem) *model.MarketSplit {
+	var best *model.MarketSplit
+	bestPriced := len(estimates[0].Items)
+	bestTotal := estimates[0].Total
+
+	for a := 0; a < len(estimates); a++ {
+		for b := a + 1; b < len(estimates); b++ {
+			candidate := splitBetween(estimates[a], estimates[b], items)
+			if len(candidate.Markets[0].Items) == 0 || len(candidate.Markets[1].Items) == 0 {
+				continue
+			}
+			priced := len(candidate.Markets[0].Items) + len(candidate.Markets[1].Items)
+			if priced > bestPriced || (priced == bestPriced && candidate.Total < bestTotal) {
+				best = &candidate
+				bestPriced = priced
+				bestTotal = candidate.Total
+			}
+		}
+	}
+
+	return best
+}
+
+func splitBetween(first, second model.MarketEstimate, items []model.PurchaseItem) model.MarketSplit {
+	firstItems := make(map[int64]model.MarketItemEstimate, len(first.Items))
+	for _, item := range first.Items {
+		firstItems[item.ItemId] = item
+	}
+	secondItems := make(map[int64]model.MarketItemEstimate, len(second.Items))
+	for _, item := range second.Items {
+		secondItems[item.ItemId] = item
+	}
+
+	split := model.MarketSplit{
+		Markets: []model.MarketEstimate{
+			{Market: first.Market, Items: []model.MarketItemEstimate{}, MissingItemIds: []int64{}},
+			{Market: second.Market, Items: []model.MarketItemEstimate{}, MissingItemIds: []int64{}},
+		},
+		MissingItemIds: []int64{},
+	}
+	for _, item := range items {
+		firstItem, inFirst := firstItems[*item.Id]
+		secondItem, inSecond := secondItems[*item.Id]
+
+		var target *model.MarketEstimate
+		var chosen model.MarketItemEstimate
+		switch {
+		case inFirst && (!inSecond || firstItem.Total <= secondItem.Total):
+			target, chosen = &split.Markets[0], firstItem
+		case inSecond:
+			target, chosen = &split.Markets[1], secondItem
+		default:
+			split.MissingItemIds = append(split.MissingItemIds, *item.Id)
+			continue
+		}
+		target.Items = append(target.Items, chosen)
+		target.Total += chosen.Total
+		split.Total += chosen.Total
+	}
+
+	return split
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"github.com/ronistone/market-list/src/model"
+	"reflect"
+	"testing"
+)
+
+func int64Pointer(value int64) *int64 {
+	return &value
+}
+
+// recommendationItems returns one item of quantity 1 per product, the item id being the product id.
+func recommendationItems(productIds ...int64) []model.PurchaseItem {
+	items := make([]model.PurchaseItem, len(productIds))
+	for i, productId := range productIds {
+		items[i] = model.PurchaseItem{
+			Id:       int64Pointer(productId),
+			Product:  model.Product{Id: int64Pointer(productId)},
+			Quantity: 1,
+		}
+	}
+	return items
+}
+
+// marketEstimate prices the items at the market with the given unit price of each product.
+func marketEstimate(marketId int64, items []model.PurchaseItem, unitPrices map[int64]int64) model.MarketEstimate {
+	prices := make(map[productMarket]model.PriceObservation, len(unitPrices))
+	for productId, price := range unitPrices {
+		prices[productMarket{productId, marketId}] = model.PriceObservation{ProductId: productId, MarketId: marketId, Price: price}
+	}
+	return estimateAtMarket(model.Market{Id: int64Pointer(marketId)}, items, prices)
+}
+
+func estimateItemIds(estimate model.MarketEstimate) []int64 {
+	ids := []int64{}
+	for _, item := range estimate.Items {
+		ids = append(ids, item.ItemId)
+	}
+	return ids
+}
+
+func TestSplitBetween(t *testing.T) {
+	items := recommendationItems(1, 2, 3, 4, 5)
+	first := marketEstimate(10, items, map[int64]int64{1: 100, 2: 300, 3: 200})
+	second := marketEstimate(20, items, map[int64]int64{2: 250, 3: 200, 4: 400})
+
+	split := splitBetween(first, second, items)
+
+	tests := []struct {
+		name   string
+		got    interface{}
+		wanted interface{}
+	}{
+		{"first market items, ties stay at the first market", estimateItemIds(split.Markets[0]), []int64{1, 3}},
+		{"second market items", estimateItemIds(split.Markets[1]), []int64{2, 4}},
+		{"first market total", split.Markets[0].Total, int64(300)},
+		{"second market total", split.Markets[1].Total, int64(650)},
+		{"total", split.Total, int64(950)},
+		{"items missing at both markets", split.MissingItemIds, []int64{5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !reflect.DeepEqual(test.got, test.wanted) {
+				t.Errorf("got %v, want %v", test.got, test.wanted)
+			}
+		})
+	}
+}
+
+func TestBestSplit(t *testing.T) {
+	items := recommendationItems(1, 2)
+
+	tests := []struct {
+		name      string
+		estimates []model.MarketEstimate
+		marketIds []int64
+		total     int64
+	}{
+		{
+			name: "no priced items",
+			estimates: []model.MarketEstimate{
+				marketEstimate(10, items, nil),
+				marketEstimate(20, items, nil),
+			},
+		},
+		{
+			name: "single market",
+			estimates: []model.MarketEstimate{
+				marketEstimate(10, items, map[int64]int64{1: 100, 2: 100}),
+			},
+		},
+		{
+			name: "cheapest market is cheaper for every item",
+			estimates: []model.MarketEstimate{
+				marketEstimate(10, items, map[int64]int64{1: 100, 2: 100}),
+				marketEstimate(20, items, map[int64]int64{1: 200, 2: 200}),
+			},
+		},
+		{
+			name: "each market is cheaper for one item",
+			estimates: []model.MarketEstimate{
+				marketEstimate(10, items, map[int64]int64{1: 100, 2: 300}),
+				marketEstimate(20, items, map[int64]int64{1: 200, 2: 150}),
+			},
+			marketIds: []int64{10, 20},
+			total:     250,
+		},
+		{
+			name: "split prices more items even when it costs more",
+			estimates: []model.MarketEstimate{
+				marketEstimate(10, items, map[int64]int64{1: 100}),
+				marketEstimate(20, items, map[int64]int64{2: 900}),
+			},
+			marketIds: []int64{10, 20},
+			total:     1000,
+		},
+		{
+			name: "best pair among three markets",
+			estimates: []model.MarketEstimate{
+				marketEstimate(10, items, map[int64]int64{1: 100, 2: 300}),
+				marketEstimate(20, items, map[int64]int64{1: 300, 2: 250}),
+				marketEstimate(30, items, map[int64]int64{1: 500, 2: 50}),
+			},
+			marketIds: []int64{10, 30},
+			total:     150,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			split := bestSplit(test.estimates, items)
+			if test.marketIds == nil {
+				if split != nil {
+					t.Fatalf("split = %+v, want none", *split)
+				}
+				return
+			}
+			if split == nil {
+				t.Fatalf("split = nil, want markets %v", test.marketIds)
+			}
+			marketIds := []int64{*split.Markets[0].Market.Id, *split.Markets[1].Market.Id}
+			if !reflect.DeepEqual(marketIds, test.marketIds) {
+				t.Errorf("markets = %v, want %v", marketIds, test.marketIds)
+			}
+			if split.Total != test.total {
+				t.Errorf("total = %d, want %d", split.Total, test.total)
+			}
+		})
+	}
+}
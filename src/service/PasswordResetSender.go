@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type PasswordResetSender interface {
+	SendPasswordReset(ctx context.Context, user model.User, token string, expiresAt time.Time) error
+}
+
+const (
+	PASSWORD_RESET_SENDER_LOG  = "log"
+	PASSWORD_RESET_SENDER_SMTP = "smtp"
+)
+
+// LogPasswordResetSender writes the reset token to the request logger. Anyone reading the logs can use the token, so
+// it is only meant for local development and has to be selected explicitly.
+type LogPasswordResetSender struct{}
+
+func CreateLogPasswordResetSender() PasswordResetSender {
+	return &LogPasswordResetSender{}
+}
+
+func (l LogPasswordResetSender) SendPasswordReset(ctx context.Context, user model.User, token string, expiresAt time.Time) error {
+	util.Logger(ctx).Warnf("Password reset token for user (%v): %s (expires at %s), do not use the log sender in production",
+		*user.Id, token, expiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// PASSWORD_RESET_URL_TOKEN is replaced by the token in the reset url, e.g.
+// "https://market-list.example/reset?token={token}".
+const PASSWORD_RESET_URL_TOKEN = "{token}"
+
+// SmtpPasswordResetSender mails the reset link to the user.
+type SmtpPasswordResetSender struct {
+	Address  string
+	Auth     smtp.Auth
+	From     string
+	ResetUrl string
+}
+
+func CreateSmtpPasswordResetSender(host, port, username, password, from, resetUrl string) (PasswordResetSender, error) {
+	if len(host) == 0 || len(from) == 0 {
+		return nil, errors.New("smtp password reset sender needs a host and a from address")
+	}
+	if !strings.Contains(resetUrl, PASSWORD_RESET_URL_TOKEN) {
+		return nil, fmt.Errorf("password reset url must contain %s", PASSWORD_RESET_URL_TOKEN)
+	}
+
+	var auth smtp.Auth
+	if len(username) > 0 {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SmtpPasswordResetSender{
+		Address:  net.JoinHostPort(host, port),
+		Auth:     auth,
+		From:     from,
+		ResetUrl: resetUrl,
+	}, nil
+}
+
+func (s SmtpPasswordResetSender) SendPasswordReset(ctx context.Context, user model.User, token string, expiresAt time.Time) error {
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: Reset your Market List password\r\n\r\n"+
+		"Open the link below to choose a new password:\r\n\r\n%s\r\n\r\nThe link expires at %s.\r\n",
+		s.From, user.Email, strings.ReplaceAll(s.ResetUrl, PASSWORD_RESET_URL_TOKEN, token), expiresAt.Format(time.RFC1123))
+
+	err := smtp.SendMail(s.Address, s.Auth, s.From, []string{user.Email}, []byte(message))
+	if err != nil {
+		util.Logger(ctx).Errorf("Failed to send password reset to user (%v): %v", *user.Id, err)
+		return util.MakeErrorUnknown(errors.New("failed to send password reset"))
+	}
+
+	util.Logger(ctx).Infof("Sent password reset to user (%v)", *user.Id)
+	return nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/repository"
+	"github.com/ronistone/market-list/src/util"
+	"math"
+)
+
+type PriceService interface {
+	GetProductPrices(ctx context.Context, productId int64, filter model.PriceFilter) (model.ProductPriceHistory, error)
+	GetProductPricesByEan(ctx context.Context, ean string, filter model.PriceFilter) (model.ProductPriceHistory, error)
+	GetLatestPrices(ctx context.Context, productIds []int64) ([]model.PriceObservation, error)
+}
+
+type Price struct {
+	PriceRepository repository.PriceRepository
+	ProductService  ProductService
+}
+
+func CreatePriceService(priceRepository repository.PriceRepository, productService ProductService) PriceService {
+	return &Price{
+		PriceRepository: priceRepository,
+		ProductService:  productService,
+	}
+}
+
+func (p Price) GetProductPrices(ctx context.Context, productId int64, filter model.PriceFilter) (model.ProductPriceHistory, error) {
+	product, err := p.ProductService.GetById(ctx, productId)
+	if err != nil {
+		return model.ProductPriceHistory{}, err
+	}
+	return p.getPriceHistory(ctx, product, filter)
+}
+
+func (p Price) GetProductPricesByEan(ctx context.Context, ean string, filter model.PriceFilter) (model.ProductPriceHistory, error) {
+	product, err := p.ProductService.GetByEan(ctx, ean)
+	if err != nil {
+		return model.ProductPriceHistory{}, err
+	}
+	return p.getPriceHistory(ctx, product, filter)
+}
+
+// GetLatestPrices returns the last price the user paid for each of the products at each market.
+func (p Price) GetLatestPrices(ctx context.Context, productIds []int64) ([]model.PriceObservation, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return nil, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return p.PriceRepository.ListLatestPrices(ctx, *userId, productIds)
+}
+
+// getPriceHistory splits the prices the user paid for the product in one series per market.
+func (p Price) getPriceHistory(ctx context.Context, product model.Product, filter model.PriceFilter) (model.ProductPriceHistory, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.ProductPriceHistory{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return model.ProductPriceHistory{}, util.MakeError(util.INVALID_INPUT, "from must be before to")
+	}
+
+	observations, err := p.PriceRepository.ListProductPrices(ctx, *userId, *product.Id, filter)
+	if err != nil {
+		return model.ProductPriceHistory{}, err
+	}
+
+	history := model.ProductPriceHistory{
+		Product: product,
+		From:    filter.From,
+		To:      filter.To,
+		Markets: []model.MarketPriceHistory{},
+	}
+	for _, observation := range observations {
+		last := len(history.Markets) - 1
+		if last < 0 || *history.Markets[last].Market.Id != observation.MarketId {
+			marketId := observation.MarketId
+			history.Markets = append(history.Markets, model.MarketPriceHistory{
+				Market: model.Market{Id: &marketId, Name: observation.MarketName},
+			})
+			last++
+		}
+		history.Markets[last].Prices = append(history.Markets[last].Prices, observation)
+	}
+	for i := range history.Markets {
+		summarizePrices(&history.Markets[i])
+	}
+
+	return history, nil
+}
+
+// summarizePrices fills the statistics of a series ordered by time. The average is rounded to the cent.
+func summarizePrices(series *model.MarketPriceHistory) {
+	var sum int64
+	series.Min = math.MaxInt64
+	series.Max = math.MinInt64
+	for _, observation := range series.Prices {
+		sum += observation.Price
+		if observation.Price < series.Min {
+			series.Min = observation.Price
+		}
+		if observation.Price > series.Max {
+			series.Max = observation.Price
+		}
+	}
+	series.Average = int64(math.Round(float64(sum) / float64(len(series.Prices))))
+
+	last := series.Prices[len(series.Prices)-1]
+	series.Last = last.Price
+	series.LastObservedAt = last.ObservedAt
+}
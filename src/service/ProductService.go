@@ -8,7 +8,7 @@ import (
 
 type ProductService interface {
 	Create(ctx context.Context, product model.Product) (model.Product, error)
-	Update(ctx context.Context, product model.Product) (model.Product, error)
+	Update(ctx context.Context, product model.Product, expectedVersion *int64) (model.Product, error)
 	GetByName(ctx context.Context, name string) ([]model.Product, error)
 	GetByEan(ctx context.Context, ean string) (model.Product, error)
 	GetById(ctx context.Context, id int64) (model.Product, error)
@@ -28,8 +28,8 @@ func (p Product) Create(ctx context.Context, product model.Product) (model.Produ
 	return p.ProductRepository.CreateProduct(ctx, product)
 }
 
-func (p Product) Update(ctx context.Context, product model.Product) (model.Product, error) {
-	return p.ProductRepository.UpdateProduct(ctx, product)
+func (p Product) Update(ctx context.Context, product model.Product, expectedVersion *int64) (model.Product, error) {
+	return p.ProductRepository.UpdateProduct(ctx, product, expectedVersion)
 }
 
 func (p Product) GetByName(ctx context.Context, name string) ([]model.Product, error) {
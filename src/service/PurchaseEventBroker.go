@@ -0,0 +1,180 @@
+package service
+
+import (
+	"github.com/ronistone/market-list/src/model"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	PURCHASE_EVENT_HISTORY_SIZE     = 100
+	PURCHASE_EVENT_SUBSCRIBER_QUEUE = 32
+	// PURCHASE_EVENT_HISTORY_TTL is how long the history of a purchase without subscribers is kept after its last event.
+	PURCHASE_EVENT_HISTORY_TTL = 30 * time.Minute
+)
+
+type PurchaseEventBroker interface {
+	Publish(event model.PurchaseEvent)
+	Subscribe(purchaseId int64, lastEventId *int64) *PurchaseSubscription
+}
+
+// PurchaseSubscription streams the events of a purchase. Replay holds the events missed since the last event id
+// given on subscribe, and Resync is set when they are no longer available and the client must reload the purchase.
+// Events is closed when the subscriber falls behind, the client is expected to reconnect with its last event id.
+type PurchaseSubscription struct {
+	Events <-chan model.PurchaseEvent
+	Replay []model.PurchaseEvent
+	Resync bool
+	Close  func()
+}
+
+type purchaseEventSubscriber struct {
+	events chan model.PurchaseEvent
+}
+
+type purchaseEventHistory struct {
+	events      []model.PurchaseEvent
+	evictedId   int64
+	publishedAt time.Time
+}
+
+// MemoryPurchaseEventBroker keeps subscribers and a short event history in memory, so it only delivers events
+// published by the same server instance. Event ids carry a random epoch of the process in their high 32 bits, so ids
+// from another process (or from before a restart) are recognized and answered with a resync.
+type MemoryPurchaseEventBroker struct {
+	mutex       sync.Mutex
+	epoch       int64
+	nextId      int64
+	prunedId    int64
+	prunedAt    time.Time
+	history     map[int64]*purchaseEventHistory
+	subscribers map[int64]map[*purchaseEventSubscriber]bool
+}
+
+func CreateMemoryPurchaseEventBroker() PurchaseEventBroker {
+	epoch := int64(rand.Int31())
+	return &MemoryPurchaseEventBroker{
+		epoch:       epoch,
+		nextId:      epoch<<32 + 1,
+		prunedAt:    time.Now(),
+		history:     make(map[int64]*purchaseEventHistory),
+		subscribers: make(map[int64]map[*purchaseEventSubscriber]bool),
+	}
+}
+
+func (b *MemoryPurchaseEventBroker) Publish(event model.PurchaseEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	event.Id = b.nextId
+	b.nextId++
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	history, ok := b.history[event.PurchaseId]
+	if !ok {
+		history = &purchaseEventHistory{}
+		b.history[event.PurchaseId] = history
+	}
+	history.events = append(history.events, event)
+	history.publishedAt = event.CreatedAt
+	if len(history.events) > PURCHASE_EVENT_HISTORY_SIZE {
+		history.evictedId = history.events[0].Id
+		history.events = history.events[1:]
+	}
+
+	for subscriber := range b.subscribers[event.PurchaseId] {
+		select {
+		case subscriber.events <- event:
+		default:
+			b.unsubscribe(event.PurchaseId, subscriber)
+		}
+	}
+
+	if event.Type == model.PURCHASE_EVENT_PURCHASE_DELETED {
+		b.prune(event.PurchaseId)
+	}
+	b.pruneIdle()
+}
+
+// prune drops the history of the purchase. Clients resuming from before its last event get a resync.
+func (b *MemoryPurchaseEventBroker) prune(purchaseId int64) {
+	history := b.history[purchaseId]
+	if history == nil {
+		return
+	}
+	if last := history.events[len(history.events)-1].Id; last > b.prunedId {
+		b.prunedId = last
+	}
+	delete(b.history, purchaseId)
+}
+
+// pruneIdle drops, at most once per TTL, the histories of purchases without subscribers nor events for a TTL.
+func (b *MemoryPurchaseEventBroker) pruneIdle() {
+	now := time.Now()
+	if now.Sub(b.prunedAt) < PURCHASE_EVENT_HISTORY_TTL {
+		return
+	}
+	b.prunedAt = now
+
+	for purchaseId, history := range b.history {
+		if len(b.subscribers[purchaseId]) == 0 && now.Sub(history.publishedAt) >= PURCHASE_EVENT_HISTORY_TTL {
+			b.prune(purchaseId)
+		}
+	}
+}
+
+func (b *MemoryPurchaseEventBroker) Subscribe(purchaseId int64, lastEventId *int64) *PurchaseSubscription {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	subscription := &PurchaseSubscription{}
+	if lastEventId != nil {
+		history := b.history[purchaseId]
+		switch {
+		case *lastEventId>>32 != b.epoch || *lastEventId >= b.nextId:
+			subscription.Resync = true
+		case history == nil && *lastEventId < b.prunedId:
+			subscription.Resync = true
+		case history != nil && *lastEventId < history.evictedId:
+			subscription.Resync = true
+		case history != nil:
+			for _, event := range history.events {
+				if event.Id > *lastEventId {
+					subscription.Replay = append(subscription.Replay, event)
+				}
+			}
+		}
+	}
+
+	subscriber := &purchaseEventSubscriber{
+		events: make(chan model.PurchaseEvent, PURCHASE_EVENT_SUBSCRIBER_QUEUE),
+	}
+	if b.subscribers[purchaseId] == nil {
+		b.subscribers[purchaseId] = make(map[*purchaseEventSubscriber]bool)
+	}
+	b.subscribers[purchaseId][subscriber] = true
+
+	subscription.Events = subscriber.events
+	subscription.Close = func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		b.unsubscribe(purchaseId, subscriber)
+	}
+
+	return subscription
+}
+
+func (b *MemoryPurchaseEventBroker) unsubscribe(purchaseId int64, subscriber *purchaseEventSubscriber) {
+	subscribers := b.subscribers[purchaseId]
+	if !subscribers[subscriber] {
+		return
+	}
+	delete(subscribers, subscriber)
+	close(subscriber.events)
+	if len(subscribers) == 0 {
+		delete(b.subscribers, purchaseId)
+	}
+}
@@ -2,37 +2,74 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"github.com/ronistone/market-list/src/model"
 	"github.com/ronistone/market-list/src/repository"
 	"github.com/ronistone/market-list/src/util"
 	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DEFAULT_PURCHASE_PAGE_SIZE = 20
+	MAX_PURCHASE_PAGE_SIZE     = 100
+	MAX_PURCHASE_ITEM_BATCH    = 500
 )
 
 type PurchaseService interface {
 	CreatePurchase(ctx context.Context, purchase model.Purchase) (model.Purchase, error)
-	AddItem(ctx context.Context, purchaseId int64, purchaseItem model.PurchaseItem) (model.Purchase, error)
+	AddItem(ctx context.Context, purchaseId int64, purchaseItem model.PurchaseItem, merge bool) (model.Purchase, error)
+	CreateItem(ctx context.Context, purchaseId int64, purchaseItem model.PurchaseItem, merge bool) (model.PurchaseItem, error)
+	ConsolidateItems(ctx context.Context, purchaseId int64) (model.Purchase, error)
 	RemoveItem(ctx context.Context, purchaseId int64, purchaseItemId int64) (model.Purchase, error)
-	UpdateItem(ctx context.Context, purchaseId int64, purchaseItemId int64, item model.PurchaseItem) (model.Purchase, error)
+	UpdateItem(ctx context.Context, purchaseId int64, purchaseItemId int64, item model.PurchaseItem, expectedVersion *int64) (model.Purchase, error)
+	ApplyItemBatch(ctx context.Context, purchaseId int64, batch model.PurchaseItemBatch) (model.PurchaseItemBatchResult, error)
+	MoveItem(ctx context.Context, purchaseId int64, purchaseItemId int64, move model.PurchaseItemMove) (model.Purchase, error)
 	GetPurchase(ctx context.Context, id int64) (model.Purchase, error)
-	GetAllPurchase(ctx context.Context) ([]model.Purchase, error)
+	GetPurchaseSorted(ctx context.Context, id int64, sort model.PurchaseItemSort) (model.Purchase, error)
+	GetPurchaseGroupedByCategory(ctx context.Context, id int64, sort model.PurchaseItemSort) (model.Purchase, error)
+	GetAllPurchase(ctx context.Context, filter model.PurchaseFilter) (model.PurchasePage, error)
+	UpdatePurchase(ctx context.Context, id int64, update model.PurchaseUpdate) (model.Purchase, error)
+	ReplacePurchase(ctx context.Context, id int64, update model.PurchaseUpdate) (model.Purchase, error)
+	ClonePurchase(ctx context.Context, id int64, options model.PurchaseCloneOptions) (model.Purchase, error)
 	DeletePurchase(ctx context.Context, id int64) error
+	LeavePurchase(ctx context.Context, id int64) error
+	UpdateParticipantRole(ctx context.Context, purchaseId, userId int64, role model.PurchaseRole) (model.Purchase, error)
+	RemoveParticipant(ctx context.Context, purchaseId, userId int64) (model.Purchase, error)
 	GetItem(ctx context.Context, purchaseId int64, purchaseItemId int64) (model.PurchaseItem, error)
+	SubscribeEvents(ctx context.Context, purchaseId int64, lastEventId *int64) (*PurchaseSubscription, error)
 }
 type Purchase struct {
-	PurchaseRepository repository.PurchaseRepository
-	ProductService     ProductService
-	UserService        UserService
+	PurchaseRepository  repository.PurchaseRepository
+	ProductService      ProductService
+	UserService         UserService
+	PurchaseEventBroker PurchaseEventBroker
+	CategoryService     CategoryService
+	MarketLayoutService MarketLayoutService
+	PriceService        PriceService
+	AlertService        AlertService
 }
 
 func CreatePurchaseService(
 	purchaseRepository repository.PurchaseRepository,
 	productService ProductService,
 	userService UserService,
+	purchaseEventBroker PurchaseEventBroker,
+	categoryService CategoryService,
+	marketLayoutService MarketLayoutService,
+	priceService PriceService,
+	alertService AlertService,
 ) PurchaseService {
 	return &Purchase{
-		PurchaseRepository: purchaseRepository,
-		ProductService:     productService,
-		UserService:        userService,
+		PurchaseRepository:  purchaseRepository,
+		ProductService:      productService,
+		UserService:         userService,
+		PurchaseEventBroker: purchaseEventBroker,
+		CategoryService:     categoryService,
+		MarketLayoutService: marketLayoutService,
+		PriceService:        priceService,
+		AlertService:        alertService,
 	}
 }
 
@@ -42,15 +79,13 @@ func (p Purchase) CreatePurchase(ctx context.Context, purchase model.Purchase) (
 		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
 	}
 
-	isUserFound := false
+	// Only the creator joins the new purchase, the other participants are invited and join when they accept.
 	for _, user := range purchase.Users {
-		if user.Id == userId {
-			isUserFound = true
+		if user.Id == nil || *user.Id != *userId {
+			return model.Purchase{}, util.MakeError(util.INVALID_INPUT, "participants must be invited after the purchase is created")
 		}
 	}
-	if !isUserFound {
-		purchase.Users = append(purchase.Users, model.User{Id: userId})
-	}
+	purchase.Users = []model.User{{Id: userId, Role: model.PURCHASE_ROLE_OWNER}}
 
 	created, err := p.PurchaseRepository.CreatePurchase(ctx, purchase)
 	if err != nil {
@@ -62,35 +97,99 @@ func (p Purchase) CreatePurchase(ctx context.Context, purchase model.Purchase) (
 	return p.GetPurchase(ctx, *created.Id)
 }
 
-func (p Purchase) AddItem(ctx context.Context, purchaseId int64, purchaseItem model.PurchaseItem) (model.Purchase, error) {
+// AddItem adds the item to the purchase. With merge, an unpurchased item of the same product already on the list
+// gets its quantity increased instead of a duplicate being inserted.
+func (p Purchase) AddItem(ctx context.Context, purchaseId int64, purchaseItem model.PurchaseItem, merge bool) (model.Purchase, error) {
+	_, err := p.CreateItem(ctx, purchaseId, purchaseItem, merge)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	return p.GetPurchase(ctx, purchaseId)
+}
+
+// CreateItem adds the item to the purchase like AddItem, but returns only the created or merged item.
+func (p Purchase) CreateItem(ctx context.Context, purchaseId int64, purchaseItem model.PurchaseItem, merge bool) (model.PurchaseItem, error) {
 	userId := util.GetUserFromContext(ctx)
 	if userId == nil {
-		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+		return model.PurchaseItem{}, util.MakeError(util.FORBIDDEN, "Forbidden")
 	}
-	_, err := p.PurchaseRepository.GetPurchaseById(ctx, *userId, purchaseId)
+	_, err := p.checkRole(ctx, *userId, purchaseId, "add items", model.PURCHASE_ROLE_OWNER, model.PURCHASE_ROLE_EDITOR)
 	if err != nil {
-		return model.Purchase{}, err
+		return model.PurchaseItem{}, err
 	}
 
 	if purchaseItem.Quantity == 0 {
 		purchaseItem.Quantity = 1
 	}
+	err = normalizeItemDetails(&purchaseItem)
+	if err != nil {
+		return model.PurchaseItem{}, err
+	}
 
 	product, err := p.processProduct(ctx, purchaseItem)
 	if err != nil {
-		return model.Purchase{}, err
+		return model.PurchaseItem{}, err
 	}
 	purchaseItem.Product = product
 
-	_, err = p.PurchaseRepository.AddPurchaseItem(ctx, *userId, purchaseId, purchaseItem)
+	if merge {
+		existing, err := p.PurchaseRepository.FindMergeablePurchaseItem(ctx, *userId, purchaseId, product)
+		if err != nil {
+			return model.PurchaseItem{}, err
+		}
+		if existing != nil {
+			merged, err := p.PurchaseRepository.MergePurchaseItem(ctx, *userId, purchaseId, *existing.Id, purchaseItem)
+			if err != nil {
+				return model.PurchaseItem{}, err
+			}
+			util.Logger(ctx).Infof("Merged product (%v) into item (%v) of purchase (%v)", *product.Id, *merged.Id, purchaseId)
+			p.publishItemEvent(model.PURCHASE_EVENT_ITEM_UPDATED, *userId, purchaseId, *merged.Id, &merged)
+			if purchaseItem.Price != nil {
+				p.evaluatePriceAlerts(ctx, merged)
+			}
+			return merged, nil
+		}
+	}
+
+	created, err := p.PurchaseRepository.AddPurchaseItem(ctx, *userId, purchaseId, purchaseItem)
 	if err != nil {
-		return model.Purchase{}, err
+		return model.PurchaseItem{}, err
 	}
 
-	return p.GetPurchase(ctx, purchaseId)
+	p.publishItemEvent(model.PURCHASE_EVENT_ITEM_ADDED, *userId, purchaseId, *created.Id, &created)
+	if created.Price != nil {
+		p.evaluatePriceAlerts(ctx, created)
+	}
+
+	return created, nil
 }
 
+// evaluatePriceAlerts checks a newly recorded price against the watches on the product. Alerts never fail the change.
+func (p Purchase) evaluatePriceAlerts(ctx context.Context, item model.PurchaseItem) {
+	err := p.AlertService.EvaluatePrice(ctx, item)
+	if err != nil {
+		util.Logger(ctx).Warnf("Failed to evaluate price alerts of item (%v): %v", *item.Id, err)
+	}
+}
+
+// processProduct creates or updates the product of the item when resolveProduct found changes to write.
 func (p Purchase) processProduct(ctx context.Context, purchaseItem model.PurchaseItem) (model.Product, error) {
+	product, changed := p.resolveProduct(ctx, purchaseItem)
+	if !changed {
+		return product, nil
+	}
+	if product.Id == nil {
+		return p.ProductService.Create(ctx, product)
+	}
+	return p.ProductService.Update(ctx, product, &product.Version)
+}
+
+// resolveProduct finds the product of the item by id or EAN and applies the fields of the item to it, without writing
+// anything. Products are shared by every user, so an existing product is only changed when the item carries the
+// version of the product it was read at, like the If-Match of PUT /v1/product/:id. Otherwise the stored product is
+// used as is. changed tells whether the product must be written: created when it has no id, or updated at its Version.
+func (p Purchase) resolveProduct(ctx context.Context, purchaseItem model.PurchaseItem) (model.Product, bool) {
 	var productFound *model.Product
 	var product = purchaseItem.Product
 
@@ -110,23 +209,58 @@ func (p Purchase) processProduct(ctx context.Context, purchaseItem model.Purchas
 	}
 
 	if productFound == nil {
-		createdProduct, err := p.ProductService.Create(ctx, product)
-		if err != nil {
-			return model.Product{}, err
+		product.Id = nil
+		return product, true
+	}
+
+	update := *productFound
+	update.Name = product.Name
+	update.Ean = product.Ean
+	update.Size = product.Size
+	update.Unit = product.Unit
+	if product.CategoryId != nil {
+		update.CategoryId = product.CategoryId
+	}
+
+	sameVersion := product.Id != nil && *product.Id == *productFound.Id && product.Version != 0
+	if !sameVersion || isSameProduct(update, *productFound) {
+		return *productFound, false
+	}
+	update.Version = product.Version
+	return update, true
+}
+
+func isSameProduct(a, b model.Product) bool {
+	sameEan := (a.Ean == nil && b.Ean == nil) || (a.Ean != nil && b.Ean != nil && *a.Ean == *b.Ean)
+	sameCategory := (a.CategoryId == nil && b.CategoryId == nil) ||
+		(a.CategoryId != nil && b.CategoryId != nil && *a.CategoryId == *b.CategoryId)
+	return sameEan && sameCategory && a.Name == b.Name && a.Size == b.Size && a.Unit == b.Unit
+}
+
+// checkRole returns the role of the user on the purchase, failing with FORBIDDEN when it is not one of allowed.
+func (p Purchase) checkRole(ctx context.Context, userId, purchaseId int64, action string, allowed ...model.PurchaseRole) (model.PurchaseRole, error) {
+	role, err := p.PurchaseRepository.GetPurchaseRole(ctx, userId, purchaseId)
+	if err != nil {
+		return "", err
+	}
+
+	for _, allowedRole := range allowed {
+		if role == allowedRole {
+			return role, nil
 		}
-		productFound = &createdProduct
-	} else {
-		productFound.Name = product.Name
-		productFound.Ean = product.Ean
-		productFound.Size = product.Size
-		productFound.Unit = product.Unit
-		updatedProduct, err := p.ProductService.Update(ctx, *productFound)
-		if err != nil {
-			return model.Product{}, err
+	}
+
+	return role, util.MakeError(util.FORBIDDEN, fmt.Sprintf("%s participants are not allowed to %s", role, action))
+}
+
+func countOwners(users []model.User) int {
+	owners := 0
+	for _, user := range users {
+		if user.Role == model.PURCHASE_ROLE_OWNER {
+			owners++
 		}
-		productFound = &updatedProduct
 	}
-	return *productFound, nil
+	return owners
 }
 
 func (p Purchase) calculateRealPrice(productInstance model.PurchaseItem) *float64 {
@@ -142,60 +276,352 @@ func (p Purchase) RemoveItem(ctx context.Context, purchaseId int64, purchaseItem
 	if userId == nil {
 		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
 	}
-	_, err := p.PurchaseRepository.RemovePurchaseItem(ctx, *userId, purchaseId, purchaseItemId)
+	_, err := p.checkRole(ctx, *userId, purchaseId, "remove items", model.PURCHASE_ROLE_OWNER, model.PURCHASE_ROLE_EDITOR)
 	if err != nil {
 		return model.Purchase{}, err
 	}
+	_, err = p.PurchaseRepository.RemovePurchaseItem(ctx, *userId, purchaseId, purchaseItemId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	p.publishItemEvent(model.PURCHASE_EVENT_ITEM_REMOVED, *userId, purchaseId, purchaseItemId, nil)
+
 	return p.GetPurchase(ctx, purchaseId)
 }
 
-func (p Purchase) UpdateItem(ctx context.Context, purchaseId int64, purchaseItemId int64, item model.PurchaseItem) (model.Purchase, error) {
+// MoveItem places the item right before or right after another item of the purchase in the manual order.
+func (p Purchase) MoveItem(ctx context.Context, purchaseId int64, purchaseItemId int64, move model.PurchaseItemMove) (model.Purchase, error) {
 	userId := util.GetUserFromContext(ctx)
 	if userId == nil {
 		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
 	}
-	_, err := p.PurchaseRepository.GetPurchaseItemById(ctx, *userId, purchaseId, purchaseItemId)
+	if (move.Before == nil) == (move.After == nil) {
+		return model.Purchase{}, util.MakeError(util.INVALID_INPUT, "exactly one of before or after must be given")
+	}
+	if (move.Before != nil && *move.Before == purchaseItemId) || (move.After != nil && *move.After == purchaseItemId) {
+		return model.Purchase{}, util.MakeError(util.INVALID_INPUT, "an item cannot be moved next to itself")
+	}
+	_, err := p.checkRole(ctx, *userId, purchaseId, "reorder items", model.PURCHASE_ROLE_OWNER, model.PURCHASE_ROLE_EDITOR)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	err = p.PurchaseRepository.MovePurchaseItem(ctx, purchaseId, purchaseItemId, move)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	purchase, err := p.GetPurchaseSorted(ctx, purchaseId, model.PURCHASE_ITEM_SORT_MANUAL)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	for i := range purchase.Items {
+		if *purchase.Items[i].Id == purchaseItemId {
+			p.publishItemEvent(model.PURCHASE_EVENT_ITEM_UPDATED, *userId, purchaseId, purchaseItemId, &purchase.Items[i])
+		}
+	}
+
+	return purchase, nil
+}
+
+// UpdateItem overwrites the item. When expectedVersion is set, the update fails with PRECONDITION_FAILED
+// if somebody else changed the item since the client read that version.
+func (p Purchase) UpdateItem(ctx context.Context, purchaseId int64, purchaseItemId int64, item model.PurchaseItem, expectedVersion *int64) (model.Purchase, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	role, err := p.checkRole(ctx, *userId, purchaseId, "update items",
+		model.PURCHASE_ROLE_OWNER, model.PURCHASE_ROLE_EDITOR, model.PURCHASE_ROLE_VIEWER)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+	existing, err := p.PurchaseRepository.GetPurchaseItemById(ctx, *userId, purchaseId, purchaseItemId)
 	if err != nil {
 		return model.Purchase{}, util.MakeError(util.NOT_FOUND, "Failed to get purchase Item")
 	}
+	if expectedVersion != nil && existing.Version != *expectedVersion {
+		return model.Purchase{}, util.MakeError(util.PRECONDITION_FAILED,
+			fmt.Sprintf("Purchase Item %d was modified, expected version %d but found %d", purchaseItemId, *expectedVersion, existing.Version))
+	}
+
+	if role == model.PURCHASE_ROLE_VIEWER {
+		if !isCheckOnlyUpdate(existing, item) {
+			return model.Purchase{}, util.MakeError(util.FORBIDDEN, "VIEWER participants are only allowed to check items")
+		}
+		existing.Purchased = item.Purchased
+		item = existing
+	} else {
+		err = normalizeItemDetails(&item)
+		if err != nil {
+			return model.Purchase{}, err
+		}
+		product, err := p.processProduct(ctx, item)
+		if err != nil {
+			return model.Purchase{}, err
+		}
+		item.Product = product
+	}
 
-	product, err := p.processProduct(ctx, item)
+	err = p.PurchaseRepository.UpdatePurchaseItem(ctx, *userId, purchaseId, purchaseItemId, item, expectedVersion)
 	if err != nil {
 		return model.Purchase{}, err
 	}
-	item.Product = product
 
-	err = p.PurchaseRepository.UpdatePurchaseItem(ctx, *userId, purchaseId, purchaseItemId, item)
+	purchase, err := p.GetPurchase(ctx, purchaseId)
 	if err != nil {
 		return model.Purchase{}, err
 	}
-	return p.GetPurchase(ctx, purchaseId)
+
+	priceChanged := item.Price != nil && (existing.Price == nil || *existing.Price != *item.Price)
+	for i := range purchase.Items {
+		if *purchase.Items[i].Id == purchaseItemId {
+			p.publishItemEvent(model.PURCHASE_EVENT_ITEM_UPDATED, *userId, purchaseId, purchaseItemId, &purchase.Items[i])
+			if priceChanged {
+				p.evaluatePriceAlerts(ctx, purchase.Items[i])
+			}
+		}
+	}
+
+	return purchase, nil
 }
 
-func (p Purchase) GetPurchase(ctx context.Context, id int64) (model.Purchase, error) {
+// ConsolidateItems merges the unpurchased items of the same product into the oldest of them, summing the quantities.
+func (p Purchase) ConsolidateItems(ctx context.Context, purchaseId int64) (model.Purchase, error) {
 	userId := util.GetUserFromContext(ctx)
 	if userId == nil {
 		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
 	}
-	purchase, err := p.PurchaseRepository.GetPurchaseByIdFetchItems(ctx, *userId, id)
+	_, err := p.checkRole(ctx, *userId, purchaseId, "consolidate items", model.PURCHASE_ROLE_OWNER, model.PURCHASE_ROLE_EDITOR)
 	if err != nil {
 		return model.Purchase{}, err
 	}
 
-	purchase.TotalSpent = 0
-	purchase.TotalExpected = 0
+	merged, removed, err := p.PurchaseRepository.ConsolidatePurchaseItems(ctx, purchaseId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
 
-	for _, item := range purchase.Items {
+	purchase, err := p.GetPurchase(ctx, purchaseId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	for _, itemId := range removed {
+		p.publishItemEvent(model.PURCHASE_EVENT_ITEM_REMOVED, *userId, purchaseId, itemId, nil)
+	}
+	for _, itemId := range merged {
+		for i := range purchase.Items {
+			if *purchase.Items[i].Id == itemId {
+				p.publishItemEvent(model.PURCHASE_EVENT_ITEM_UPDATED, *userId, purchaseId, itemId, &purchase.Items[i])
+			}
+		}
+	}
+
+	util.Logger(ctx).Infof("Consolidated %d duplicated items into %d on purchase (%v)", len(removed), len(merged), purchaseId)
 
-		if item.Price == nil {
+	return purchase, nil
+}
+
+// ApplyItemBatch applies a list of item operations in a single transaction and returns the purchase once.
+// Operations that cannot even be prepared, like an invalid role, fail without reaching the database. Products are only
+// looked up while preparing, they are written in the transaction with the items.
+func (p Purchase) ApplyItemBatch(ctx context.Context, purchaseId int64, batch model.PurchaseItemBatch) (model.PurchaseItemBatchResult, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.PurchaseItemBatchResult{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	if len(batch.Operations) == 0 {
+		return model.PurchaseItemBatchResult{}, util.MakeError(util.INVALID_INPUT, "at least one operation is required")
+	}
+	if len(batch.Operations) > MAX_PURCHASE_ITEM_BATCH {
+		return model.PurchaseItemBatchResult{}, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("at most %d operations are accepted per batch", MAX_PURCHASE_ITEM_BATCH))
+	}
+	role, err := p.checkRole(ctx, *userId, purchaseId, "change items",
+		model.PURCHASE_ROLE_OWNER, model.PURCHASE_ROLE_EDITOR, model.PURCHASE_ROLE_VIEWER)
+	if err != nil {
+		return model.PurchaseItemBatchResult{}, err
+	}
+
+	results := make([]model.PurchaseItemOperationResult, len(batch.Operations))
+	var pending []int
+	var operations []model.PurchaseItemOperation
+	for i, operation := range batch.Operations {
+		results[i] = model.PurchaseItemOperationResult{Index: i, Type: operation.Type, ItemId: operation.ItemId}
+		operation, err = p.prepareItemOperation(ctx, role, operation)
+		if err != nil {
+			message := err.Error()
+			if mkError, ok := err.(*util.MarketListError); ok {
+				message = mkError.Message
+			}
+			results[i].Status = model.PURCHASE_ITEM_OPERATION_FAILED
+			results[i].Error = &message
 			continue
 		}
+		pending = append(pending, i)
+		operations = append(operations, operation)
+	}
 
-		if item.Purchased {
-			purchase.TotalSpent += *item.Price * int64(item.Quantity)
+	if batch.Atomic && len(pending) < len(batch.Operations) {
+		for _, i := range pending {
+			results[i].Status = model.PURCHASE_ITEM_OPERATION_ROLLED_BACK
 		}
+		operations = nil
+	}
 
-		purchase.TotalExpected += *item.Price * int64(item.Quantity)
+	if len(operations) > 0 {
+		applied, err := p.PurchaseRepository.ApplyPurchaseItemOperations(ctx, purchaseId, operations, batch.Atomic)
+		if err != nil {
+			return model.PurchaseItemBatchResult{}, err
+		}
+		for j, result := range applied {
+			result.Index = pending[j]
+			results[pending[j]] = result
+		}
+	}
+
+	purchase, err := p.GetPurchase(ctx, purchaseId)
+	if err != nil {
+		return model.PurchaseItemBatchResult{}, err
+	}
+
+	p.publishBatchEvents(ctx, *userId, purchase, results)
+
+	util.Logger(ctx).Infof("User (%v) applied a batch of %d item operations on purchase (%v)", *userId, len(batch.Operations), purchaseId)
+
+	return model.PurchaseItemBatchResult{Purchase: purchase, Results: results}, nil
+}
+
+// prepareItemOperation validates the operation against the role of the user and resolves its product.
+func (p Purchase) prepareItemOperation(ctx context.Context, role model.PurchaseRole, operation model.PurchaseItemOperation) (model.PurchaseItemOperation, error) {
+	if role == model.PURCHASE_ROLE_VIEWER && operation.Type != model.PURCHASE_ITEM_OPERATION_CHECK {
+		return operation, util.MakeError(util.FORBIDDEN, "VIEWER participants are only allowed to check items")
+	}
+
+	switch operation.Type {
+	case model.PURCHASE_ITEM_OPERATION_ADD, model.PURCHASE_ITEM_OPERATION_UPDATE:
+		if operation.Item == nil {
+			return operation, util.MakeError(util.INVALID_INPUT, "item is required")
+		}
+		if operation.Type == model.PURCHASE_ITEM_OPERATION_UPDATE && operation.ItemId == nil {
+			return operation, util.MakeError(util.INVALID_INPUT, "itemId is required")
+		}
+		item := *operation.Item
+		if item.Quantity == 0 {
+			item.Quantity = 1
+		}
+		err := normalizeItemDetails(&item)
+		if err != nil {
+			return operation, err
+		}
+		item.Product, operation.ProductChanged = p.resolveProduct(ctx, item)
+		operation.Item = &item
+	case model.PURCHASE_ITEM_OPERATION_REMOVE, model.PURCHASE_ITEM_OPERATION_CHECK:
+		if operation.ItemId == nil {
+			return operation, util.MakeError(util.INVALID_INPUT, "itemId is required")
+		}
+	default:
+		return operation, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("invalid operation type %s", operation.Type))
+	}
+
+	return operation, nil
+}
+
+// publishBatchEvents publishes the changes of the applied operations and evaluates the price alerts of the items
+// added or updated with a price. Alerts already raised for the same price are not raised again.
+func (p Purchase) publishBatchEvents(ctx context.Context, userId int64, purchase model.Purchase, results []model.PurchaseItemOperationResult) {
+	items := make(map[int64]*model.PurchaseItem)
+	for i := range purchase.Items {
+		items[*purchase.Items[i].Id] = &purchase.Items[i]
+	}
+
+	for _, result := range results {
+		if result.Status != model.PURCHASE_ITEM_OPERATION_APPLIED || result.ItemId == nil {
+			continue
+		}
+		item := items[*result.ItemId]
+		switch result.Type {
+		case model.PURCHASE_ITEM_OPERATION_ADD:
+			p.publishItemEvent(model.PURCHASE_EVENT_ITEM_ADDED, userId, *purchase.Id, *result.ItemId, item)
+		case model.PURCHASE_ITEM_OPERATION_REMOVE:
+			p.publishItemEvent(model.PURCHASE_EVENT_ITEM_REMOVED, userId, *purchase.Id, *result.ItemId, nil)
+		default:
+			p.publishItemEvent(model.PURCHASE_EVENT_ITEM_UPDATED, userId, *purchase.Id, *result.ItemId, item)
+		}
+
+		isPriced := result.Type == model.PURCHASE_ITEM_OPERATION_ADD || result.Type == model.PURCHASE_ITEM_OPERATION_UPDATE
+		if isPriced && item != nil && item.Price != nil {
+			p.evaluatePriceAlerts(ctx, *item)
+		}
+	}
+}
+
+// isCheckOnlyUpdate reports whether update changes nothing but the purchased flag of existing.
+func isCheckOnlyUpdate(existing, update model.PurchaseItem) bool {
+	if update.Quantity != 0 && update.Quantity != existing.Quantity {
+		return false
+	}
+	if update.Price != nil && (existing.Price == nil || *update.Price != *existing.Price) {
+		return false
+	}
+	if update.Product.Id != nil && (existing.Product.Id == nil || *update.Product.Id != *existing.Product.Id) {
+		return false
+	}
+	if update.Product.Ean != nil && (existing.Product.Ean == nil || *update.Product.Ean != *existing.Product.Ean) {
+		return false
+	}
+	if update.Notes != nil {
+		existingNotes := ""
+		if existing.Notes != nil {
+			existingNotes = *existing.Notes
+		}
+		if strings.TrimSpace(*update.Notes) != existingNotes {
+			return false
+		}
+	}
+	if update.Priority != nil && (existing.Priority == nil || *update.Priority != *existing.Priority) {
+		return false
+	}
+	if update.Optional != nil && (existing.Optional == nil || *update.Optional != *existing.Optional) {
+		return false
+	}
+	return true
+}
+
+// normalizeItemDetails trims the notes and validates notes and priority. Empty notes clear the stored ones.
+func normalizeItemDetails(item *model.PurchaseItem) error {
+	if item.Notes != nil {
+		notes := strings.TrimSpace(*item.Notes)
+		if utf8.RuneCountInString(notes) > model.MAX_PURCHASE_ITEM_NOTES_LENGTH {
+			return util.MakeError(util.INVALID_INPUT, fmt.Sprintf("notes must have at most %d characters", model.MAX_PURCHASE_ITEM_NOTES_LENGTH))
+		}
+		item.Notes = &notes
+	}
+	if item.Priority != nil && (*item.Priority < model.PURCHASE_ITEM_PRIORITY_LOW || *item.Priority > model.PURCHASE_ITEM_PRIORITY_URGENT) {
+		return util.MakeError(util.INVALID_INPUT, fmt.Sprintf("priority must be between %d and %d",
+			model.PURCHASE_ITEM_PRIORITY_LOW, model.PURCHASE_ITEM_PRIORITY_URGENT))
+	}
+	return nil
+}
+
+// GetPurchase returns the purchase with its items in the default order. The walking order needs the layout and the
+// learned check order of the market, so it is only computed when asked for through GetPurchaseSorted.
+func (p Purchase) GetPurchase(ctx context.Context, id int64) (model.Purchase, error) {
+	return p.GetPurchaseSorted(ctx, id, model.PURCHASE_ITEM_SORT_DEFAULT)
+}
+
+// GetPurchaseSorted returns the purchase with its items in the given order. The walking order falls back to
+// the default one for purchases without a market.
+func (p Purchase) GetPurchaseSorted(ctx context.Context, id int64, sort model.PurchaseItemSort) (model.Purchase, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	purchase, err := p.PurchaseRepository.GetPurchaseByIdFetchItems(ctx, *userId, id, sort)
+	if err != nil {
+		return model.Purchase{}, err
 	}
 
 	users, err := p.UserService.GetUsersByPurchaseId(ctx, *purchase.Id)
@@ -205,15 +631,270 @@ func (p Purchase) GetPurchase(ctx context.Context, id int64) (model.Purchase, er
 
 	purchase.Users = users
 
+	err = p.estimatePrices(ctx, &purchase)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	if sort == model.PURCHASE_ITEM_SORT_WALKING && purchase.MarketId != nil {
+		purchase.Items, err = p.MarketLayoutService.SortItems(ctx, *purchase.MarketId, purchase.Items)
+		if err != nil {
+			return model.Purchase{}, err
+		}
+	}
+
 	return purchase, nil
 }
 
-func (p Purchase) GetAllPurchase(ctx context.Context) ([]model.Purchase, error) {
+// estimatePrices gives the unpriced items the last price paid for their product, preferably at the market of the
+// purchase, and adds them to the estimated total of the purchase.
+func (p Purchase) estimatePrices(ctx context.Context, purchase *model.Purchase) error {
+	totalEstimated := purchase.TotalExpected
+	purchase.TotalEstimated = &totalEstimated
+
+	var productIds []int64
+	for _, item := range purchase.Items {
+		if item.Price == nil && item.Product.Id != nil {
+			productIds = append(productIds, *item.Product.Id)
+		}
+	}
+	if len(productIds) == 0 {
+		return nil
+	}
+
+	observations, err := p.PriceService.GetLatestPrices(ctx, productIds)
+	if err != nil {
+		return err
+	}
+	atMarket := make(map[int64]model.PriceObservation)
+	latest := make(map[int64]model.PriceObservation)
+	for _, observation := range observations {
+		if purchase.MarketId != nil && observation.MarketId == *purchase.MarketId {
+			atMarket[observation.ProductId] = observation
+		}
+		if last, ok := latest[observation.ProductId]; !ok || observation.ObservedAt.After(last.ObservedAt) {
+			latest[observation.ProductId] = observation
+		}
+	}
+
+	for i := range purchase.Items {
+		item := &purchase.Items[i]
+		if item.Price != nil || item.Product.Id == nil {
+			continue
+		}
+		source := model.PRICE_ESTIMATE_SOURCE_PURCHASE_MARKET
+		observation, ok := atMarket[*item.Product.Id]
+		if !ok {
+			source = model.PRICE_ESTIMATE_SOURCE_OTHER_MARKET
+			observation, ok = latest[*item.Product.Id]
+		}
+		if !ok {
+			continue
+		}
+
+		price := observation.Price
+		item.EstimatedPrice = &price
+		item.EstimatedPriceSource = &model.PriceEstimateSource{
+			Type:       source,
+			MarketId:   observation.MarketId,
+			MarketName: observation.MarketName,
+			PurchaseId: observation.PurchaseId,
+			ObservedAt: observation.ObservedAt,
+		}
+		totalEstimated += price * int64(item.Quantity)
+	}
+
+	return nil
+}
+
+// GetPurchaseGroupedByCategory returns the purchase with its items split in category groups, ordered by category name
+// and with the uncategorized group last. Items of uncategorized products go to the group of their suggested category.
+func (p Purchase) GetPurchaseGroupedByCategory(ctx context.Context, id int64, sort model.PurchaseItemSort) (model.Purchase, error) {
+	purchase, err := p.GetPurchaseSorted(ctx, id, sort)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	categories, err := p.CategoryService.List(ctx)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+	suggestions, err := p.CategoryService.SuggestForPurchase(ctx, id)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	groupIndex := make(map[int64]int, len(categories))
+	for i := range categories {
+		groupIndex[*categories[i].Id] = i
+	}
+	groups := make([]model.PurchaseItemGroup, len(categories)+1)
+	for i := range categories {
+		groups[i].Category = &categories[i]
+	}
+	uncategorized := len(categories)
+
+	for _, item := range purchase.Items {
+		categoryId := item.Product.CategoryId
+		if categoryId == nil && item.Product.Id != nil {
+			if suggested, ok := suggestions[*item.Product.Id]; ok {
+				categoryId = &suggested
+				item.SuggestedCategoryId = &suggested
+			}
+		}
+
+		index := uncategorized
+		if categoryId != nil {
+			if i, ok := groupIndex[*categoryId]; ok {
+				index = i
+			}
+		}
+		addToGroup(&groups[index], item)
+	}
+
+	purchase.Groups = nil
+	for _, group := range groups {
+		if len(group.Items) > 0 {
+			purchase.Groups = append(purchase.Groups, group)
+		}
+	}
+	purchase.Items = nil
+
+	return purchase, nil
+}
+
+func addToGroup(group *model.PurchaseItemGroup, item model.PurchaseItem) {
+	group.Items = append(group.Items, item)
+	group.ItemCount++
+	if item.Purchased {
+		group.PurchasedItemCount++
+	}
+	if item.Price == nil {
+		if item.EstimatedPrice != nil {
+			group.TotalEstimated += *item.EstimatedPrice * int64(item.Quantity)
+		}
+		return
+	}
+	total := *item.Price * int64(item.Quantity)
+	group.TotalEstimated += total
+	group.TotalExpected += total
+	if item.Purchased {
+		group.TotalSpent += total
+	}
+}
+
+func (p Purchase) GetAllPurchase(ctx context.Context, filter model.PurchaseFilter) (model.PurchasePage, error) {
 	userId := util.GetUserFromContext(ctx)
 	if userId == nil {
-		return []model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+		return model.PurchasePage{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+
+	if len(filter.Sort) == 0 {
+		filter.Sort = model.PURCHASE_SORT_CREATED_AT
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DEFAULT_PURCHASE_PAGE_SIZE
+	}
+	if filter.Limit > MAX_PURCHASE_PAGE_SIZE {
+		filter.Limit = MAX_PURCHASE_PAGE_SIZE
 	}
-	return p.PurchaseRepository.ListPurchase(ctx, *userId)
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return model.PurchasePage{}, util.MakeError(util.INVALID_INPUT, "from must be before to")
+	}
+
+	return p.PurchaseRepository.ListPurchase(ctx, *userId, filter)
+}
+
+// UpdatePurchase only changes the fields of the update that are set.
+func (p Purchase) UpdatePurchase(ctx context.Context, id int64, update model.PurchaseUpdate) (model.Purchase, error) {
+	return p.writePurchase(ctx, id, update, false)
+}
+
+// ReplacePurchase overwrites the metadata of the purchase: the name is required, a missing market clears the market
+// and a missing isFavorite unsets it.
+func (p Purchase) ReplacePurchase(ctx context.Context, id int64, update model.PurchaseUpdate) (model.Purchase, error) {
+	if update.Name == nil {
+		return model.Purchase{}, util.MakeError(util.INVALID_INPUT, "Purchase name is required")
+	}
+	return p.writePurchase(ctx, id, update, true)
+}
+
+func (p Purchase) writePurchase(ctx context.Context, id int64, update model.PurchaseUpdate, replace bool) (model.Purchase, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	_, err := p.checkRole(ctx, *userId, id, "update the purchase", model.PURCHASE_ROLE_OWNER, model.PURCHASE_ROLE_EDITOR)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+	purchase, err := p.PurchaseRepository.GetPurchaseById(ctx, *userId, id)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if len(name) == 0 {
+			return model.Purchase{}, util.MakeError(util.INVALID_INPUT, "Purchase name cannot be empty")
+		}
+		purchase.Name = name
+	}
+	if update.MarketId != nil || replace {
+		purchase.MarketId = update.MarketId
+	}
+	if update.IsFavorite != nil {
+		purchase.IsFavorite = *update.IsFavorite
+	} else if replace {
+		purchase.IsFavorite = false
+	}
+
+	err = p.PurchaseRepository.UpdatePurchase(ctx, purchase)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	util.Logger(ctx).Infof("Updated purchase (%v) %s", *purchase.Id, purchase.Name)
+
+	return p.getPurchaseAndPublish(ctx, *userId, id)
+}
+
+func (p Purchase) ClonePurchase(ctx context.Context, id int64, options model.PurchaseCloneOptions) (model.Purchase, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	source, err := p.GetPurchase(ctx, id)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	clone := model.Purchase{
+		Name:  source.Name,
+		Users: []model.User{{Id: userId, Role: model.PURCHASE_ROLE_OWNER}},
+	}
+	if options.Name != nil {
+		name := strings.TrimSpace(*options.Name)
+		if len(name) == 0 {
+			return model.Purchase{}, util.MakeError(util.INVALID_INPUT, "Purchase name cannot be empty")
+		}
+		clone.Name = name
+	}
+	if options.KeepMarket {
+		clone.MarketId = source.MarketId
+	}
+	if options.KeepParticipants && source.Role != model.PURCHASE_ROLE_OWNER {
+		return model.Purchase{}, util.MakeError(util.FORBIDDEN, fmt.Sprintf("%s participants are not allowed to manage participants", source.Role))
+	}
+
+	created, err := p.PurchaseRepository.ClonePurchase(ctx, *userId, id, clone, options)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	util.Logger(ctx).Infof("Cloned purchase (%v) into (%v) %s", id, *created.Id, created.Name)
+
+	return p.GetPurchase(ctx, *created.Id)
 }
 
 func (p Purchase) DeletePurchase(ctx context.Context, id int64) error {
@@ -221,6 +902,10 @@ func (p Purchase) DeletePurchase(ctx context.Context, id int64) error {
 	if userId == nil {
 		return util.MakeError(util.FORBIDDEN, "Forbidden")
 	}
+	_, err := p.checkRole(ctx, *userId, id, "delete the purchase", model.PURCHASE_ROLE_OWNER)
+	if err != nil {
+		return err
+	}
 	purchase, err := p.GetPurchase(ctx, id)
 	if err != nil {
 		return err
@@ -233,7 +918,118 @@ func (p Purchase) DeletePurchase(ctx context.Context, id int64) error {
 		}
 	}
 
-	return p.PurchaseRepository.DeletePurchase(ctx, *userId, id)
+	err = p.PurchaseRepository.DeletePurchase(ctx, *userId, id)
+	if err != nil {
+		return err
+	}
+
+	p.PurchaseEventBroker.Publish(model.PurchaseEvent{
+		Type:       model.PURCHASE_EVENT_PURCHASE_DELETED,
+		PurchaseId: id,
+		UserId:     *userId,
+	})
+
+	return nil
+}
+
+func (p Purchase) LeavePurchase(ctx context.Context, id int64) error {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	purchase, err := p.GetPurchase(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if len(purchase.Users) <= 1 {
+		return util.MakeError(util.INVALID_INPUT, "the last participant cannot leave the purchase, delete it instead")
+	}
+
+	if purchase.Role == model.PURCHASE_ROLE_OWNER && countOwners(purchase.Users) <= 1 {
+		return util.MakeError(util.INVALID_INPUT, "the last owner cannot leave the purchase, transfer the ownership first")
+	}
+
+	err = p.PurchaseRepository.RemovePurchaseUser(ctx, id, *userId)
+	if err != nil {
+		return err
+	}
+
+	util.Logger(ctx).Infof("User (%v) left purchase (%v)", *userId, id)
+
+	p.PurchaseEventBroker.Publish(model.PurchaseEvent{
+		Type:       model.PURCHASE_EVENT_PURCHASE_CHANGED,
+		PurchaseId: id,
+		UserId:     *userId,
+	})
+
+	return nil
+}
+
+func (p Purchase) UpdateParticipantRole(ctx context.Context, purchaseId, userId int64, role model.PurchaseRole) (model.Purchase, error) {
+	currentUserId := util.GetUserFromContext(ctx)
+	if currentUserId == nil {
+		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	if !role.IsValid() {
+		return model.Purchase{}, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("invalid role %s", role))
+	}
+	_, err := p.checkRole(ctx, *currentUserId, purchaseId, "manage participants", model.PURCHASE_ROLE_OWNER)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	participantRole, err := p.PurchaseRepository.GetPurchaseRole(ctx, userId, purchaseId)
+	if err != nil {
+		return model.Purchase{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("User %d does not participate in the purchase", userId))
+	}
+
+	if participantRole == model.PURCHASE_ROLE_OWNER && role != model.PURCHASE_ROLE_OWNER {
+		users, err := p.UserService.GetUsersByPurchaseId(ctx, purchaseId)
+		if err != nil {
+			return model.Purchase{}, err
+		}
+		if countOwners(users) <= 1 {
+			return model.Purchase{}, util.MakeError(util.INVALID_INPUT, "the purchase must keep at least one owner")
+		}
+	}
+
+	err = p.PurchaseRepository.UpdatePurchaseUserRole(ctx, purchaseId, userId, role)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	util.Logger(ctx).Infof("User (%v) changed role of user (%v) on purchase (%v) to %s", *currentUserId, userId, purchaseId, role)
+
+	return p.getPurchaseAndPublish(ctx, *currentUserId, purchaseId)
+}
+
+func (p Purchase) RemoveParticipant(ctx context.Context, purchaseId, userId int64) (model.Purchase, error) {
+	currentUserId := util.GetUserFromContext(ctx)
+	if currentUserId == nil {
+		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	if userId == *currentUserId {
+		return model.Purchase{}, util.MakeError(util.INVALID_INPUT, "use leave to remove yourself from the purchase")
+	}
+	_, err := p.checkRole(ctx, *currentUserId, purchaseId, "manage participants", model.PURCHASE_ROLE_OWNER)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	_, err = p.PurchaseRepository.GetPurchaseRole(ctx, userId, purchaseId)
+	if err != nil {
+		return model.Purchase{}, util.MakeError(util.NOT_FOUND, fmt.Sprintf("User %d does not participate in the purchase", userId))
+	}
+
+	err = p.PurchaseRepository.RemovePurchaseUser(ctx, purchaseId, userId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	util.Logger(ctx).Infof("User (%v) removed user (%v) from purchase (%v)", *currentUserId, userId, purchaseId)
+
+	return p.getPurchaseAndPublish(ctx, *currentUserId, purchaseId)
 }
 
 func (p Purchase) GetItem(ctx context.Context, purchaseId int64, purchaseItemId int64) (model.PurchaseItem, error) {
@@ -243,3 +1039,47 @@ func (p Purchase) GetItem(ctx context.Context, purchaseId int64, purchaseItemId
 	}
 	return p.PurchaseRepository.GetPurchaseItemById(ctx, *userId, purchaseId, purchaseItemId)
 }
+
+func (p Purchase) SubscribeEvents(ctx context.Context, purchaseId int64, lastEventId *int64) (*PurchaseSubscription, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return nil, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	_, err := p.PurchaseRepository.GetPurchaseRole(ctx, *userId, purchaseId)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.PurchaseEventBroker.Subscribe(purchaseId, lastEventId), nil
+}
+
+func (p Purchase) publishItemEvent(eventType model.PurchaseEventType, userId, purchaseId, itemId int64, item *model.PurchaseItem) {
+	p.PurchaseEventBroker.Publish(model.PurchaseEvent{
+		Type:       eventType,
+		PurchaseId: purchaseId,
+		UserId:     userId,
+		ItemId:     &itemId,
+		Item:       item,
+	})
+}
+
+// getPurchaseAndPublish loads the purchase and notifies its subscribers that it changed.
+// The event carries the purchase without items and without the role of the user who changed it.
+func (p Purchase) getPurchaseAndPublish(ctx context.Context, userId, purchaseId int64) (model.Purchase, error) {
+	purchase, err := p.GetPurchase(ctx, purchaseId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	summary := purchase
+	summary.Items = nil
+	summary.Role = ""
+	p.PurchaseEventBroker.Publish(model.PurchaseEvent{
+		Type:       model.PURCHASE_EVENT_PURCHASE_CHANGED,
+		PurchaseId: purchaseId,
+		UserId:     userId,
+		Purchase:   &summary,
+	})
+
+	return purchase, nil
+}
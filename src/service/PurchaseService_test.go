@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/util"
+	"reflect"
+	"testing"
+)
+
+// storedProducts only knows the products of its slice.
+type storedProducts struct {
+	ProductService
+	products []model.Product
+}
+
+func (s storedProducts) GetById(ctx context.Context, id int64) (model.Product, error) {
+	for _, product := range s.products {
+		if *product.Id == id {
+			return product, nil
+		}
+	}
+	return model.Product{}, util.MakeError(util.NOT_FOUND, "Product not found")
+}
+
+func (s storedProducts) GetByEan(ctx context.Context, ean string) (model.Product, error) {
+	for _, product := range s.products {
+		if product.Ean != nil && *product.Ean == ean {
+			return product, nil
+		}
+	}
+	return model.Product{}, util.MakeError(util.NOT_FOUND, "Product not found")
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func TestResolveProduct(t *testing.T) {
+	stored := model.Product{Id: int64Pointer(1), Ean: stringPointer("789"), Name: "Rice", Unit: "g", Size: 1000, Version: 4}
+	purchase := Purchase{ProductService: storedProducts{products: []model.Product{stored}}}
+
+	renamed := stored
+	renamed.Name = "Brown rice"
+
+	tests := []struct {
+		name    string
+		product model.Product
+		want    model.Product
+		changed bool
+	}{
+		{
+			name:    "new product",
+			product: model.Product{Id: int64Pointer(2), Name: "Beans", Unit: "g", Size: 500},
+			want:    model.Product{Name: "Beans", Unit: "g", Size: 500},
+			changed: true,
+		},
+		{
+			name:    "same fields",
+			product: stored,
+			want:    stored,
+		},
+		{
+			name:    "changed at the current version",
+			product: model.Product{Id: int64Pointer(1), Ean: stringPointer("789"), Name: "Brown rice", Unit: "g", Size: 1000, Version: 4},
+			want:    renamed,
+			changed: true,
+		},
+		{
+			name:    "changed at an older version",
+			product: model.Product{Id: int64Pointer(1), Ean: stringPointer("789"), Name: "Brown rice", Unit: "g", Size: 1000, Version: 3},
+			want:    model.Product{Id: int64Pointer(1), Ean: stringPointer("789"), Name: "Brown rice", Unit: "g", Size: 1000, Version: 3},
+			changed: true,
+		},
+		{
+			name:    "changed without a version",
+			product: model.Product{Id: int64Pointer(1), Ean: stringPointer("789"), Name: "Brown rice", Unit: "g", Size: 1000},
+			want:    stored,
+		},
+		{
+			name:    "found by EAN without an id",
+			product: model.Product{Ean: stringPointer("789"), Name: "Brown rice", Unit: "g", Size: 1000, Version: 4},
+			want:    stored,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			product, changed := purchase.resolveProduct(context.Background(), model.PurchaseItem{Product: test.product})
+			if changed != test.changed {
+				t.Errorf("changed = %v, want %v", changed, test.changed)
+			}
+			if !reflect.DeepEqual(product, test.want) {
+				t.Errorf("product = %+v, want %+v", product, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/repository"
+	"github.com/ronistone/market-list/src/util"
+	"math"
+	"sort"
+	"time"
+)
+
+// INFLATION_BASKET_MIN_MONTHS is in how many different months a product must have been bought to be in the basket.
+// Shorter reports lower it to the number of months they cover.
+const INFLATION_BASKET_MIN_MONTHS = 3
+
+type ReportService interface {
+	GetInflationIndex(ctx context.Context, filter model.InflationFilter) (model.InflationIndex, error)
+	GetSpending(ctx context.Context, filter model.SpendingFilter) (model.SpendingReport, error)
+}
+
+type Report struct {
+	ReportRepository repository.ReportRepository
+	UserService      UserService
+}
+
+func CreateReportService(reportRepository repository.ReportRepository, userService UserService) ReportService {
+	return &Report{
+		ReportRepository: reportRepository,
+		UserService:      userService,
+	}
+}
+
+// monthlyPrices holds the average unit price of each product by month.
+type monthlyPrices map[time.Time]map[int64]float64
+
+type productSpending struct {
+	spent    int64
+	quantity int64
+}
+
+// averagePrices merges the rows of each product and month, weighting the prices by the quantity bought.
+func averagePrices(prices []model.MonthlyPrice) monthlyPrices {
+	spending := make(map[time.Time]map[int64]productSpending)
+	for _, price := range prices {
+		if spending[price.Month] == nil {
+			spending[price.Month] = make(map[int64]productSpending)
+		}
+		total := spending[price.Month][price.ProductId]
+		total.spent += price.Spent
+		total.quantity += price.Quantity
+		spending[price.Month][price.ProductId] = total
+	}
+
+	averages := make(monthlyPrices, len(spending))
+	for month, products := range spending {
+		averages[month] = make(map[int64]float64, len(products))
+		for productId, total := range products {
+			averages[month][productId] = float64(total.spent) / float64(total.quantity)
+		}
+	}
+	return averages
+}
+
+// currentUserTimezone returns the current user and the timezone their reports are grouped in.
+func (r Report) currentUserTimezone(ctx context.Context) (model.User, string, error) {
+	user, err := r.UserService.GetCurrentUser(ctx)
+	if err != nil {
+		return model.User{}, "", err
+	}
+	if len(user.Timezone) == 0 {
+		return user, DEFAULT_USER_TIMEZONE, nil
+	}
+	return user, user.Timezone, nil
+}
+
+// GetInflationIndex computes a chained monthly price index over the basket of products the user buys often, each one
+// weighted by its share of what the user spent on the basket. Every month is compared with the last price paid for
+// each product before it, so months without purchases do not break the series.
+func (r Report) GetInflationIndex(ctx context.Context, filter model.InflationFilter) (model.InflationIndex, error) {
+	user, timezone, err := r.currentUserTimezone(ctx)
+	if err != nil {
+		return model.InflationIndex{}, err
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return model.InflationIndex{}, util.MakeError(util.INVALID_INPUT, "from must be before to")
+	}
+
+	prices, err := r.ReportRepository.ListMonthlyPrices(ctx, *user.Id, timezone, filter)
+	if err != nil {
+		return model.InflationIndex{}, err
+	}
+	tagPrices, err := r.ReportRepository.ListMonthlyTagPrices(ctx, *user.Id, timezone, filter)
+	if err != nil {
+		return model.InflationIndex{}, err
+	}
+
+	basket, weights := inflationBasket(prices)
+	index := model.InflationIndex{
+		Timezone:   timezone,
+		From:       filter.From,
+		To:         filter.To,
+		Basket:     basket,
+		Markets:    []model.MarketInflation{},
+		Categories: []model.CategoryInflation{},
+		Tags:       []model.TagInflation{},
+	}
+
+	var basketPrices []model.MonthlyPrice
+	byMarket := make(map[int64][]model.MonthlyPrice)
+	byCategory := make(map[int64][]model.MonthlyPrice)
+	for _, price := range prices {
+		if _, ok := weights[price.ProductId]; !ok {
+			continue
+		}
+		basketPrices = append(basketPrices, price)
+		if byMarket[price.MarketId] == nil {
+			index.Markets = append(index.Markets, model.MarketInflation{MarketId: price.MarketId, MarketName: price.MarketName})
+		}
+		byMarket[price.MarketId] = append(byMarket[price.MarketId], price)
+
+		var categoryId int64
+		if price.CategoryId != nil {
+			categoryId = *price.CategoryId
+		}
+		if byCategory[categoryId] == nil {
+			index.Categories = append(index.Categories, model.CategoryInflation{CategoryId: price.CategoryId, CategoryName: price.CategoryName})
+		}
+		byCategory[categoryId] = append(byCategory[categoryId], price)
+	}
+	index.Overall = chainIndex(averagePrices(basketPrices), weights)
+	for i := range index.Markets {
+		market := &index.Markets[i]
+		market.InflationSeries = chainIndex(averagePrices(byMarket[market.MarketId]), weights)
+	}
+	for i := range index.Categories {
+		category := &index.Categories[i]
+		var categoryId int64
+		if category.CategoryId != nil {
+			categoryId = *category.CategoryId
+		}
+		category.InflationSeries = chainIndex(averagePrices(byCategory[categoryId]), weights)
+	}
+
+	byTag := make(map[int64][]model.MonthlyPrice)
+	for _, price := range tagPrices {
+		if _, ok := weights[price.ProductId]; !ok {
+			continue
+		}
+		if byTag[*price.TagId] == nil {
+			index.Tags = append(index.Tags, model.TagInflation{TagId: *price.TagId, TagName: *price.TagName})
+		}
+		byTag[*price.TagId] = append(byTag[*price.TagId], price)
+	}
+	for i := range index.Tags {
+		tag := &index.Tags[i]
+		tag.InflationSeries = chainIndex(averagePrices(byTag[tag.TagId]), weights)
+	}
+
+	sort.Slice(index.Markets, func(a, b int) bool { return index.Markets[a].MarketName < index.Markets[b].MarketName })
+	sort.Slice(index.Tags, func(a, b int) bool { return index.Tags[a].TagName < index.Tags[b].TagName })
+	sort.Slice(index.Categories, func(a, b int) bool {
+		categoryA, categoryB := index.Categories[a].CategoryName, index.Categories[b].CategoryName
+		if categoryA == nil || categoryB == nil {
+			return categoryB == nil && categoryA != nil
+		}
+		return *categoryA < *categoryB
+	})
+
+	return index, nil
+}
+
+// GetSpending sums the purchases of the current user by day, week, month or year of their timezone.
+func (r Report) GetSpending(ctx context.Context, filter model.SpendingFilter) (model.SpendingReport, error) {
+	user, timezone, err := r.currentUserTimezone(ctx)
+	if err != nil {
+		return model.SpendingReport{}, err
+	}
+	if len(filter.Granularity) == 0 {
+		filter.Granularity = model.SPENDING_GRANULARITY_MONTH
+	}
+	if !filter.Granularity.IsValid() {
+		return model.SpendingReport{}, util.MakeError(util.INVALID_INPUT, "invalid groupBy")
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return model.SpendingReport{}, util.MakeError(util.INVALID_INPUT, "from must be before to")
+	}
+	periods, err := r.ReportRepository.ListSpending(ctx, *user.Id, timezone, filter)
+	if err != nil {
+		return model.SpendingReport{}, err
+	}
+
+	report := model.SpendingReport{
+		Granularity: filter.Granularity,
+		Timezone:    timezone,
+		From:        filter.From,
+		To:          filter.To,
+		Periods:     []model.SpendingPeriod{},
+	}
+	for _, period := range periods {
+		withSpendingAverages(&period)
+		report.Periods = append(report.Periods, period)
+
+		report.Total.TotalSpent += period.TotalSpent
+		report.Total.TotalExpected += period.TotalExpected
+		report.Total.PurchaseCount += period.PurchaseCount
+		report.Total.Trips += period.Trips
+		report.Total.PurchasedQuantity += period.PurchasedQuantity
+	}
+	withSpendingAverages(&report.Total)
+
+	return report, nil
+}
+
+func withSpendingAverages(period *model.SpendingPeriod) {
+	if period.Trips == 0 {
+		return
+	}
+	period.AverageSpent = period.TotalSpent / period.Trips
+	period.AverageItemCount = roundTo(float64(period.PurchasedQuantity)/float64(period.Trips), 2)
+}
+
+// inflationBasket picks the products bought in enough different months and weights them by what was spent on them.
+func inflationBasket(prices []model.MonthlyPrice) ([]model.BasketProduct, map[int64]float64) {
+	allMonths := make(map[time.Time]bool)
+	months := make(map[int64]map[time.Time]bool)
+	spent := make(map[int64]int64)
+	names := make(map[int64]string)
+	for _, price := range prices {
+		allMonths[price.Month] = true
+		if months[price.ProductId] == nil {
+			months[price.ProductId] = make(map[time.Time]bool)
+		}
+		months[price.ProductId][price.Month] = true
+		spent[price.ProductId] += price.Spent
+		names[price.ProductId] = price.ProductName
+	}
+
+	minMonths := INFLATION_BASKET_MIN_MONTHS
+	if len(allMonths) < minMonths {
+		minMonths = len(allMonths)
+	}
+
+	var total int64
+	basket := []model.BasketProduct{}
+	for productId, productMonths := range months {
+		if len(productMonths) >= minMonths && spent[productId] > 0 {
+			basket = append(basket, model.BasketProduct{ProductId: productId, ProductName: names[productId], Months: len(productMonths)})
+			total += spent[productId]
+		}
+	}
+
+	weights := make(map[int64]float64, len(basket))
+	for i := range basket {
+		weight := float64(spent[basket[i].ProductId]) / float64(total)
+		weights[basket[i].ProductId] = weight
+		basket[i].Weight = roundTo(weight, 4)
+	}
+	sort.Slice(basket, func(a, b int) bool {
+		if basket[a].Weight != basket[b].Weight {
+			return basket[a].Weight > basket[b].Weight
+		}
+		return basket[a].ProductId < basket[b].ProductId
+	})
+
+	return basket, weights
+}
+
+func chainIndex(prices monthlyPrices, weights map[int64]float64) model.InflationSeries {
+	months := make([]time.Time, 0, len(prices))
+	for month := range prices {
+		months = append(months, month)
+	}
+	sort.Slice(months, func(a, b int) bool { return months[a].Before(months[b]) })
+
+	series := model.InflationSeries{Points: []model.InflationPoint{}}
+	lastPrices := make(map[int64]float64)
+	index := 100.0
+	for _, month := range months {
+		var relative, covered float64
+		for productId, price := range prices[month] {
+			weight, inBasket := weights[productId]
+			if !inBasket {
+				continue
+			}
+			if last, ok := lastPrices[productId]; ok && last > 0 {
+				relative += weight * price / last
+				covered += weight
+			}
+			lastPrices[productId] = price
+		}
+
+		point := model.InflationPoint{Month: month}
+		if covered > 0 {
+			index *= relative / covered
+			point.Change = roundTo((relative/covered-1)*100, 2)
+		}
+		point.Index = roundTo(index, 2)
+		point.Coverage = roundTo(covered, 4)
+		series.Points = append(series.Points, point)
+	}
+	series.Inflation = roundTo(index-100, 2)
+
+	return series
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
+}
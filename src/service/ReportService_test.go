@@ -0,0 +1,173 @@
+package service
+
+import (
+	"github.com/ronistone/market-list/src/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func month(number time.Month) time.Time {
+	return time.Date(2024, number, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func monthlyPrice(number time.Month, productId int64, spent int64, quantity int64) model.MonthlyPrice {
+	return model.MonthlyPrice{Month: month(number), ProductId: productId, Spent: spent, Quantity: quantity}
+}
+
+func TestInflationBasket(t *testing.T) {
+	tests := []struct {
+		name    string
+		prices  []model.MonthlyPrice
+		basket  []model.BasketProduct
+		weights map[int64]float64
+	}{
+		{
+			name:    "empty basket",
+			prices:  nil,
+			basket:  []model.BasketProduct{},
+			weights: map[int64]float64{},
+		},
+		{
+			name: "single month keeps every product",
+			prices: []model.MonthlyPrice{
+				monthlyPrice(time.January, 1, 300, 3),
+				monthlyPrice(time.January, 2, 100, 1),
+			},
+			basket: []model.BasketProduct{
+				{ProductId: 1, Months: 1, Weight: 0.75},
+				{ProductId: 2, Months: 1, Weight: 0.25},
+			},
+			weights: map[int64]float64{1: 0.75, 2: 0.25},
+		},
+		{
+			name: "products bought in too few months",
+			prices: []model.MonthlyPrice{
+				monthlyPrice(time.January, 1, 100, 1),
+				monthlyPrice(time.February, 1, 100, 1),
+				monthlyPrice(time.March, 1, 100, 1),
+				monthlyPrice(time.January, 2, 500, 1),
+				monthlyPrice(time.March, 2, 500, 1),
+			},
+			basket:  []model.BasketProduct{{ProductId: 1, Months: 3, Weight: 1}},
+			weights: map[int64]float64{1: 1},
+		},
+		{
+			name: "products without spending",
+			prices: []model.MonthlyPrice{
+				monthlyPrice(time.January, 1, 100, 1),
+				monthlyPrice(time.January, 2, 0, 1),
+			},
+			basket:  []model.BasketProduct{{ProductId: 1, Months: 1, Weight: 1}},
+			weights: map[int64]float64{1: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			basket, weights := inflationBasket(test.prices)
+			if !reflect.DeepEqual(basket, test.basket) {
+				t.Errorf("basket = %+v, want %+v", basket, test.basket)
+			}
+			if !reflect.DeepEqual(weights, test.weights) {
+				t.Errorf("weights = %v, want %v", weights, test.weights)
+			}
+		})
+	}
+}
+
+func TestChainIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		prices  monthlyPrices
+		weights map[int64]float64
+		series  model.InflationSeries
+	}{
+		{
+			name:    "empty series",
+			prices:  monthlyPrices{},
+			weights: map[int64]float64{},
+			series:  model.InflationSeries{Points: []model.InflationPoint{}},
+		},
+		{
+			name:    "single month",
+			prices:  monthlyPrices{month(time.January): {1: 10}},
+			weights: map[int64]float64{1: 1},
+			series: model.InflationSeries{Points: []model.InflationPoint{
+				{Month: month(time.January), Index: 100},
+			}},
+		},
+		{
+			name: "price rise",
+			prices: monthlyPrices{
+				month(time.February): {1: 11},
+				month(time.January):  {1: 10},
+			},
+			weights: map[int64]float64{1: 1},
+			series: model.InflationSeries{
+				Points: []model.InflationPoint{
+					{Month: month(time.January), Index: 100},
+					{Month: month(time.February), Index: 110, Change: 10, Coverage: 1},
+				},
+				Inflation: 10,
+			},
+		},
+		{
+			name: "missing prices",
+			prices: monthlyPrices{
+				month(time.January):  {1: 10, 2: 20},
+				month(time.February): {1: 12},
+				month(time.March):    {1: 12, 2: 22},
+			},
+			weights: map[int64]float64{1: 0.5, 2: 0.5},
+			series: model.InflationSeries{
+				Points: []model.InflationPoint{
+					{Month: month(time.January), Index: 100},
+					{Month: month(time.February), Index: 120, Change: 20, Coverage: 0.5},
+					{Month: month(time.March), Index: 126, Change: 5, Coverage: 1},
+				},
+				Inflation: 26,
+			},
+		},
+		{
+			name: "products outside the basket",
+			prices: monthlyPrices{
+				month(time.January):  {1: 10, 2: 5},
+				month(time.February): {1: 10, 2: 50},
+			},
+			weights: map[int64]float64{1: 1},
+			series: model.InflationSeries{
+				Points: []model.InflationPoint{
+					{Month: month(time.January), Index: 100},
+					{Month: month(time.February), Index: 100, Coverage: 1},
+				},
+			},
+		},
+		{
+			name: "month without basket products",
+			prices: monthlyPrices{
+				month(time.January):  {1: 10},
+				month(time.February): {2: 5},
+				month(time.March):    {1: 15},
+			},
+			weights: map[int64]float64{1: 1},
+			series: model.InflationSeries{
+				Points: []model.InflationPoint{
+					{Month: month(time.January), Index: 100},
+					{Month: month(time.February), Index: 100},
+					{Month: month(time.March), Index: 150, Change: 50, Coverage: 1},
+				},
+				Inflation: 50,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			series := chainIndex(test.prices, test.weights)
+			if !reflect.DeepEqual(series, test.series) {
+				t.Errorf("series = %+v, want %+v", series, test.series)
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/repository"
+	"github.com/ronistone/market-list/src/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MAX_SYNC_MUTATIONS = 500
+	// SYNC_PRUNE_INTERVAL is how often the change log and the saved mutation results are pruned.
+	SYNC_PRUNE_INTERVAL = time.Hour
+)
+
+type SyncService interface {
+	Sync(ctx context.Context, request model.SyncRequest) (model.SyncResult, error)
+}
+
+type Sync struct {
+	SyncRepository      repository.SyncRepository
+	PurchaseRepository  repository.PurchaseRepository
+	PurchaseService     PurchaseService
+	PurchaseEventBroker PurchaseEventBroker
+	AlertService        AlertService
+	Retention           time.Duration
+}
+
+func CreateSyncService(
+	syncRepository repository.SyncRepository,
+	purchaseRepository repository.PurchaseRepository,
+	purchaseService PurchaseService,
+	purchaseEventBroker PurchaseEventBroker,
+	alertService AlertService,
+	retention time.Duration,
+) SyncService {
+	return &Sync{
+		SyncRepository:      syncRepository,
+		PurchaseRepository:  purchaseRepository,
+		PurchaseService:     purchaseService,
+		PurchaseEventBroker: purchaseEventBroker,
+		AlertService:        alertService,
+		Retention:           retention,
+	}
+}
+
+// Sync applies the queued client mutations in order and then returns every change to the user's purchases
+// since the given sync token, or all of them when there is no token or its changes were already pruned.
+func (s Sync) Sync(ctx context.Context, request model.SyncRequest) (model.SyncResult, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.SyncResult{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	if len(request.Mutations) > MAX_SYNC_MUTATIONS {
+		return model.SyncResult{}, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("at most %d mutations are accepted per sync", MAX_SYNC_MUTATIONS))
+	}
+
+	var from int64
+	fullSync := request.SyncToken == nil || len(*request.SyncToken) == 0
+	if !fullSync {
+		token, err := strconv.ParseInt(*request.SyncToken, 10, 64)
+		if err != nil || token < 0 {
+			return model.SyncResult{}, util.MakeError(util.INVALID_INPUT, "invalid sync token")
+		}
+		from = token
+	}
+
+	// Changes older than the retention are pruned, a token from before them can only be answered with a full sync.
+	if s.Retention > 0 {
+		err := s.SyncRepository.PruneChanges(ctx, s.Retention, SYNC_PRUNE_INTERVAL)
+		if err != nil {
+			util.Logger(ctx).Warnf("Failed to prune the sync change log: %v", err)
+		}
+	}
+	if !fullSync {
+		pruned, err := s.SyncRepository.GetPrunedSyncToken(ctx)
+		if err != nil {
+			return model.SyncResult{}, err
+		}
+		fullSync = from < pruned
+	}
+
+	result := model.SyncResult{
+		FullSync:   fullSync,
+		Tombstones: []model.SyncTombstone{},
+		Results:    make([]model.SyncMutationResult, len(request.Mutations)),
+	}
+	for i, mutation := range request.Mutations {
+		result.Results[i] = s.applyMutation(ctx, *userId, mutation)
+	}
+
+	to, err := s.SyncRepository.GetSyncToken(ctx)
+	if err != nil {
+		return model.SyncResult{}, err
+	}
+	result.SyncToken = strconv.FormatInt(to, 10)
+
+	if fullSync {
+		result.Purchases, err = s.PurchaseRepository.ListPurchasesByIds(ctx, *userId, nil)
+		if err != nil {
+			return model.SyncResult{}, err
+		}
+		purchaseIds := make([]int64, len(result.Purchases))
+		for i, purchase := range result.Purchases {
+			purchaseIds[i] = *purchase.Id
+		}
+		result.Items, err = s.PurchaseRepository.ListPurchaseItems(ctx, *userId, purchaseIds, nil)
+		if err != nil {
+			return model.SyncResult{}, err
+		}
+		return result, nil
+	}
+
+	changes, err := s.SyncRepository.ListChanges(ctx, *userId, from, to)
+	if err != nil {
+		return model.SyncResult{}, err
+	}
+
+	err = s.loadChanges(ctx, *userId, changes, &result)
+	if err != nil {
+		return model.SyncResult{}, err
+	}
+
+	util.Logger(ctx).Infof("User (%v) synced %d changes from token %d to %d", *userId, len(changes), from, to)
+
+	return result, nil
+}
+
+// loadChanges turns the change log entries into the current state of the touched purchases and items.
+// Anything that no longer exists, or that the user can no longer see, becomes a tombstone.
+func (s Sync) loadChanges(ctx context.Context, userId int64, changes []model.SyncChange, result *model.SyncResult) error {
+	purchaseIds := make(map[int64]bool)
+	joinedPurchaseIds := make(map[int64]bool)
+	itemPurchaseIds := make(map[int64]int64)
+
+	for _, change := range changes {
+		purchaseIds[change.PurchaseId] = true
+		switch change.Entity {
+		case model.SYNC_ENTITY_PURCHASE_ITEM:
+			itemPurchaseIds[change.EntityId] = change.PurchaseId
+		case model.SYNC_ENTITY_PURCHASE_USER:
+			if change.OwnMembership {
+				joinedPurchaseIds[change.PurchaseId] = true
+			}
+		}
+	}
+
+	purchases, err := s.PurchaseRepository.ListPurchasesByIds(ctx, userId, sortedKeys(purchaseIds))
+	if err != nil {
+		return err
+	}
+	visible := make(map[int64]bool)
+	for _, purchase := range purchases {
+		visible[*purchase.Id] = true
+	}
+	result.Purchases = purchases
+
+	for _, id := range sortedKeys(purchaseIds) {
+		if !visible[id] {
+			result.Tombstones = append(result.Tombstones, model.SyncTombstone{Entity: model.SYNC_ENTITY_PURCHASE, Id: id, PurchaseId: id})
+		}
+	}
+
+	// A purchase the user has just joined is sent whole, since none of its items were synced before.
+	var fullPurchaseIds []int64
+	for _, id := range sortedKeys(joinedPurchaseIds) {
+		if visible[id] {
+			fullPurchaseIds = append(fullPurchaseIds, id)
+		}
+	}
+
+	itemIds := make(map[int64]bool)
+	for id := range itemPurchaseIds {
+		itemIds[id] = true
+	}
+
+	items, err := s.PurchaseRepository.ListPurchaseItems(ctx, userId, fullPurchaseIds, sortedKeys(itemIds))
+	if err != nil {
+		return err
+	}
+	result.Items = items
+
+	for _, item := range items {
+		delete(itemIds, *item.Id)
+	}
+	for _, id := range sortedKeys(itemIds) {
+		purchaseId := itemPurchaseIds[id]
+		if visible[purchaseId] {
+			result.Tombstones = append(result.Tombstones, model.SyncTombstone{Entity: model.SYNC_ENTITY_PURCHASE_ITEM, Id: id, PurchaseId: purchaseId})
+		}
+	}
+
+	return nil
+}
+
+// syncFinalRejections are the errors a retry of the same mutation would get again. Only they are stored with the
+// mutation, other failures are left for the client to retry.
+var syncFinalRejections = map[util.ErrorType]bool{
+	util.INVALID_INPUT:       true,
+	util.FORBIDDEN:           true,
+	util.NOT_FOUND:           true,
+	util.PRECONDITION_FAILED: true,
+}
+
+// applyMutation applies a single client mutation. A mutation already applied under the same client id
+// is not applied again and gets its original result back, so clients can safely retry a whole batch.
+func (s Sync) applyMutation(ctx context.Context, userId int64, mutation model.SyncMutation) model.SyncMutationResult {
+	if len(mutation.ClientId) > 0 {
+		previous, err := s.SyncRepository.GetMutationResult(ctx, userId, mutation.ClientId)
+		if err == nil && previous != nil {
+			return *previous
+		}
+	}
+
+	result, err := s.applyMutationInternal(ctx, userId, mutation)
+	result.ClientId = mutation.ClientId
+	if err != nil {
+		var mkError *util.MarketListError
+		if !errors.As(err, &mkError) || !syncFinalRejections[mkError.ErrorType] {
+			message := err.Error()
+			return model.SyncMutationResult{ClientId: mutation.ClientId, Status: model.SYNC_MUTATION_REJECTED, Error: &message}
+		}
+		message := mkError.Message
+		result = model.SyncMutationResult{ClientId: mutation.ClientId, Status: model.SYNC_MUTATION_REJECTED, Error: &message}
+	}
+
+	if len(mutation.ClientId) > 0 {
+		err = s.SyncRepository.SaveMutationResult(ctx, userId, result)
+		if err != nil {
+			util.Logger(ctx).Errorf("Failed to save result of mutation %s: %v", mutation.ClientId, err)
+		}
+	}
+
+	return result
+}
+
+func (s Sync) applyMutationInternal(ctx context.Context, userId int64, mutation model.SyncMutation) (model.SyncMutationResult, error) {
+	changedAt := syncChangedAt(mutation.MutatedAt)
+
+	switch mutation.Type {
+	case model.SYNC_MUTATION_ADD_ITEM:
+		if mutation.Item == nil {
+			return model.SyncMutationResult{}, util.MakeError(util.INVALID_INPUT, "item is required")
+		}
+		created, err := s.PurchaseService.CreateItem(ctx, mutation.PurchaseId, *mutation.Item, false)
+		if err != nil {
+			return model.SyncMutationResult{}, err
+		}
+		return model.SyncMutationResult{Status: model.SYNC_MUTATION_APPLIED, ItemId: created.Id}, nil
+
+	case model.SYNC_MUTATION_REMOVE_ITEM:
+		if mutation.ItemId == nil {
+			return model.SyncMutationResult{}, util.MakeError(util.INVALID_INPUT, "itemId is required")
+		}
+		_, err := s.PurchaseService.RemoveItem(ctx, mutation.PurchaseId, *mutation.ItemId)
+		if err != nil {
+			return model.SyncMutationResult{}, err
+		}
+		return model.SyncMutationResult{Status: model.SYNC_MUTATION_APPLIED, ItemId: mutation.ItemId}, nil
+
+	case model.SYNC_MUTATION_UPDATE_ITEM:
+		if mutation.ItemId == nil || mutation.ItemChanges == nil {
+			return model.SyncMutationResult{}, util.MakeError(util.INVALID_INPUT, "itemId and itemChanges are required")
+		}
+		return s.applyItemChanges(ctx, userId, mutation.PurchaseId, *mutation.ItemId, *mutation.ItemChanges, changedAt)
+
+	case model.SYNC_MUTATION_UPDATE_PURCHASE:
+		if mutation.PurchaseChanges == nil {
+			return model.SyncMutationResult{}, util.MakeError(util.INVALID_INPUT, "purchaseChanges is required")
+		}
+		return s.applyPurchaseChanges(ctx, userId, mutation.PurchaseId, *mutation.PurchaseChanges, changedAt)
+	}
+
+	return model.SyncMutationResult{}, util.MakeError(util.INVALID_INPUT, fmt.Sprintf("invalid mutation type %s", mutation.Type))
+}
+
+func (s Sync) applyItemChanges(ctx context.Context, userId, purchaseId, itemId int64, changes model.PurchaseItemChanges, changedAt time.Time) (model.SyncMutationResult, error) {
+	role, err := s.PurchaseRepository.GetPurchaseRole(ctx, userId, purchaseId)
+	if err != nil {
+		return model.SyncMutationResult{}, err
+	}
+	if role == model.PURCHASE_ROLE_VIEWER && (changes.Quantity != nil || changes.Price != nil) {
+		return model.SyncMutationResult{}, util.MakeError(util.FORBIDDEN, "VIEWER participants are only allowed to check items")
+	}
+	if changes.Quantity != nil && *changes.Quantity <= 0 {
+		return model.SyncMutationResult{}, util.MakeError(util.INVALID_INPUT, "quantity must be positive")
+	}
+
+	applied, conflicts, err := s.SyncRepository.ApplyPurchaseItemChanges(ctx, userId, purchaseId, itemId, changes, changedAt)
+	if err != nil {
+		return model.SyncMutationResult{}, err
+	}
+
+	if len(applied) > 0 {
+		item, err := s.PurchaseRepository.GetPurchaseItemById(ctx, userId, purchaseId, itemId)
+		if err == nil {
+			s.PurchaseEventBroker.Publish(model.PurchaseEvent{
+				Type:       model.PURCHASE_EVENT_ITEM_UPDATED,
+				PurchaseId: purchaseId,
+				UserId:     userId,
+				ItemId:     &itemId,
+				Item:       &item,
+			})
+			for _, field := range applied {
+				if field == "price" {
+					s.evaluatePriceAlerts(ctx, item)
+				}
+			}
+		}
+	}
+
+	return model.SyncMutationResult{
+		Status:    mutationStatus(applied, conflicts),
+		ItemId:    &itemId,
+		Applied:   applied,
+		Conflicts: conflicts,
+	}, nil
+}
+
+// evaluatePriceAlerts checks a price set by a mutation against the watches on the product, without failing the sync.
+func (s Sync) evaluatePriceAlerts(ctx context.Context, item model.PurchaseItem) {
+	err := s.AlertService.EvaluatePrice(ctx, item)
+	if err != nil {
+		util.Logger(ctx).Warnf("Failed to evaluate price alerts of item (%v): %v", *item.Id, err)
+	}
+}
+
+func (s Sync) applyPurchaseChanges(ctx context.Context, userId, purchaseId int64, changes model.PurchaseUpdate, changedAt time.Time) (model.SyncMutationResult, error) {
+	role, err := s.PurchaseRepository.GetPurchaseRole(ctx, userId, purchaseId)
+	if err != nil {
+		return model.SyncMutationResult{}, err
+	}
+	if role != model.PURCHASE_ROLE_OWNER && role != model.PURCHASE_ROLE_EDITOR {
+		return model.SyncMutationResult{}, util.MakeError(util.FORBIDDEN, fmt.Sprintf("%s participants are not allowed to update the purchase", role))
+	}
+	if changes.Name != nil {
+		name := strings.TrimSpace(*changes.Name)
+		if len(name) == 0 {
+			return model.SyncMutationResult{}, util.MakeError(util.INVALID_INPUT, "Purchase name cannot be empty")
+		}
+		changes.Name = &name
+	}
+
+	applied, conflicts, err := s.SyncRepository.ApplyPurchaseChanges(ctx, userId, purchaseId, changes, changedAt)
+	if err != nil {
+		return model.SyncMutationResult{}, err
+	}
+
+	if len(applied) > 0 {
+		purchase, err := s.PurchaseRepository.GetPurchaseById(ctx, userId, purchaseId)
+		if err == nil {
+			purchase.Role = ""
+			s.PurchaseEventBroker.Publish(model.PurchaseEvent{
+				Type:       model.PURCHASE_EVENT_PURCHASE_CHANGED,
+				PurchaseId: purchaseId,
+				UserId:     userId,
+				Purchase:   &purchase,
+			})
+		}
+	}
+
+	return model.SyncMutationResult{
+		Status:    mutationStatus(applied, conflicts),
+		Applied:   applied,
+		Conflicts: conflicts,
+	}, nil
+}
+
+// syncChangedAt is the server time a mutation is recorded with: the time the client made it while offline,
+// but never later than now, truncated to the precision stored by the database.
+func syncChangedAt(mutatedAt *time.Time) time.Time {
+	now := time.Now().UTC()
+	if mutatedAt != nil && mutatedAt.Before(now) {
+		now = mutatedAt.UTC()
+	}
+	return now.Truncate(time.Microsecond)
+}
+
+func mutationStatus(applied, conflicts []string) model.SyncMutationStatus {
+	if len(conflicts) == 0 {
+		return model.SYNC_MUTATION_APPLIED
+	}
+	if len(applied) == 0 {
+		return model.SYNC_MUTATION_CONFLICT
+	}
+	return model.SYNC_MUTATION_PARTIAL
+}
+
+func sortedKeys(values map[int64]bool) []int64 {
+	keys := make([]int64, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
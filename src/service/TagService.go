@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"github.com/ronistone/market-list/src/model"
+	"github.com/ronistone/market-list/src/repository"
+	"github.com/ronistone/market-list/src/util"
+	"strings"
+)
+
+type TagService interface {
+	Create(ctx context.Context, tag model.Tag) (model.Tag, error)
+	Update(ctx context.Context, tag model.Tag) (model.Tag, error)
+	Delete(ctx context.Context, id int64) error
+	GetById(ctx context.Context, id int64) (model.Tag, error)
+	List(ctx context.Context) ([]model.Tag, error)
+	AttachToPurchase(ctx context.Context, purchaseId, tagId int64) (model.Purchase, error)
+	DetachFromPurchase(ctx context.Context, purchaseId, tagId int64) (model.Purchase, error)
+}
+
+type Tag struct {
+	TagRepository   repository.TagRepository
+	PurchaseService PurchaseService
+}
+
+func CreateTagService(tagRepository repository.TagRepository, purchaseService PurchaseService) TagService {
+	return &Tag{
+		TagRepository:   tagRepository,
+		PurchaseService: purchaseService,
+	}
+}
+
+func validateTag(tag *model.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if len(tag.Name) == 0 {
+		return util.MakeError(util.INVALID_INPUT, "Tag name is required")
+	}
+	return nil
+}
+
+func (t Tag) Create(ctx context.Context, tag model.Tag) (model.Tag, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.Tag{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	if err := validateTag(&tag); err != nil {
+		return model.Tag{}, err
+	}
+	return t.TagRepository.CreateTag(ctx, *userId, tag)
+}
+
+func (t Tag) Update(ctx context.Context, tag model.Tag) (model.Tag, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.Tag{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	if err := validateTag(&tag); err != nil {
+		return model.Tag{}, err
+	}
+	return t.TagRepository.UpdateTag(ctx, *userId, tag)
+}
+
+func (t Tag) Delete(ctx context.Context, id int64) error {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return t.TagRepository.DeleteTag(ctx, *userId, id)
+}
+
+func (t Tag) GetById(ctx context.Context, id int64) (model.Tag, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.Tag{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return t.TagRepository.GetTagById(ctx, *userId, id)
+}
+
+func (t Tag) List(ctx context.Context) ([]model.Tag, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return []model.Tag{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return t.TagRepository.ListTags(ctx, *userId)
+}
+
+func (t Tag) AttachToPurchase(ctx context.Context, purchaseId, tagId int64) (model.Purchase, error) {
+	_, err := t.PurchaseService.GetPurchase(ctx, purchaseId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	_, err = t.GetById(ctx, tagId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	err = t.TagRepository.AttachTag(ctx, purchaseId, tagId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	return t.PurchaseService.GetPurchase(ctx, purchaseId)
+}
+
+func (t Tag) DetachFromPurchase(ctx context.Context, purchaseId, tagId int64) (model.Purchase, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.Purchase{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+
+	_, err := t.PurchaseService.GetPurchase(ctx, purchaseId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	err = t.TagRepository.DetachTag(ctx, *userId, purchaseId, tagId)
+	if err != nil {
+		return model.Purchase{}, err
+	}
+
+	return t.PurchaseService.GetPurchase(ctx, purchaseId)
+}
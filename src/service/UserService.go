@@ -2,27 +2,120 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/ronistone/market-list/src/model"
 	"github.com/ronistone/market-list/src/repository"
+	"github.com/ronistone/market-list/src/util"
+	"net/mail"
+	"strings"
+	"time"
 )
 
 type UserService interface {
 	CreateUser(ctx context.Context, user model.User) (model.User, error)
 	GetUser(ctx context.Context, id int64) (model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (model.User, error)
 	GetUsersByPurchaseId(ctx context.Context, purchaseId int64) ([]model.User, error)
+	GetCurrentUser(ctx context.Context) (model.User, error)
+	CheckAdmin(ctx context.Context, action string) error
+	UpdateCurrentUser(ctx context.Context, user model.User) (model.User, error)
+	ChangePassword(ctx context.Context, passwordChange model.PasswordChange) error
+	CheckCredentials(ctx context.Context, credentials model.Credentials) (model.User, error)
+	RequestPasswordReset(ctx context.Context, request model.PasswordResetRequest) error
+	ConfirmPasswordReset(ctx context.Context, confirm model.PasswordResetConfirm) error
 }
 type User struct {
-	UserRepository repository.UserRepository
+	UserRepository      repository.UserRepository
+	PasswordResetSender PasswordResetSender
+	PasswordCost        int
+	ResetExpiration     time.Duration
 }
 
-func CreateUserService(userRepository repository.UserRepository) UserService {
+func CreateUserService(
+	userRepository repository.UserRepository,
+	passwordResetSender PasswordResetSender,
+	passwordCost int,
+	resetExpiration time.Duration,
+) UserService {
 	return &User{
-		UserRepository: userRepository,
+		UserRepository:      userRepository,
+		PasswordResetSender: passwordResetSender,
+		PasswordCost:        passwordCost,
+		ResetExpiration:     resetExpiration,
 	}
 }
 
+func normalizeEmail(email string) (string, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Name != "" {
+		return "", util.MakeError(util.INVALID_INPUT, "invalid email")
+	}
+	return strings.ToLower(address.Address), nil
+}
+
+func validatePassword(password *string) error {
+	if password == nil || len(*password) < 8 {
+		return util.MakeError(util.INVALID_INPUT, "password must have at least 8 characters")
+	}
+	if len(*password) > 72 {
+		return util.MakeError(util.INVALID_INPUT, "password must have at most 72 characters")
+	}
+	return nil
+}
+
+// DEFAULT_USER_TIMEZONE is the timezone of the users that did not choose one.
+const DEFAULT_USER_TIMEZONE = "UTC"
+
+func (u User) validateTimezone(ctx context.Context, timezone string) error {
+	valid, err := u.UserRepository.IsValidTimezone(ctx, timezone)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return util.MakeError(util.INVALID_INPUT, "invalid timezone")
+	}
+	return nil
+}
+
+func withoutPassword(user model.User) model.User {
+	user.Password = nil
+	return user
+}
+
 func (u User) CreateUser(ctx context.Context, user model.User) (model.User, error) {
-	return u.UserRepository.CreateUser(ctx, user)
+	email, err := normalizeEmail(user.Email)
+	if err != nil {
+		return model.User{}, err
+	}
+	user.Email = email
+
+	if err = validatePassword(user.Password); err != nil {
+		return model.User{}, err
+	}
+
+	user.Timezone = strings.TrimSpace(user.Timezone)
+	if len(user.Timezone) == 0 {
+		user.Timezone = DEFAULT_USER_TIMEZONE
+	}
+	if err = u.validateTimezone(ctx, user.Timezone); err != nil {
+		return model.User{}, err
+	}
+
+	hash, err := util.HashPassword(*user.Password, u.PasswordCost)
+	if err != nil {
+		return model.User{}, util.MakeErrorUnknown(err)
+	}
+	user.Password = &hash
+
+	created, err := u.UserRepository.CreateUser(ctx, user)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	util.Logger(ctx).Infof("Created user (%v) %s", *created.Id, created.Email)
+
+	return withoutPassword(created), nil
 }
 
 func (u User) GetUser(ctx context.Context, id int64) (model.User, error) {
@@ -30,9 +123,192 @@ func (u User) GetUser(ctx context.Context, id int64) (model.User, error) {
 	if err != nil {
 		return model.User{}, err
 	}
-	return user, nil
+	return withoutPassword(user), nil
+}
+
+func (u User) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	user, err := u.UserRepository.GetUserByEmail(ctx, email)
+	if err != nil {
+		return model.User{}, err
+	}
+	return withoutPassword(user), nil
 }
 
 func (u User) GetUsersByPurchaseId(ctx context.Context, purchaseId int64) ([]model.User, error) {
-	return u.UserRepository.GetUsersByPurchaseId(ctx, purchaseId)
+	users, err := u.UserRepository.GetUsersByPurchaseId(ctx, purchaseId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i] = withoutPassword(users[i])
+	}
+	return users, nil
+}
+
+func (u User) GetCurrentUser(ctx context.Context) (model.User, error) {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return model.User{}, util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	return u.GetUser(ctx, *userId)
+}
+
+// CheckAdmin fails with FORBIDDEN unless the current user is an administrator.
+func (u User) CheckAdmin(ctx context.Context, action string) error {
+	user, err := u.GetCurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return util.MakeError(util.FORBIDDEN, fmt.Sprintf("only administrators are allowed to %s", action))
+	}
+	return nil
+}
+
+func (u User) UpdateCurrentUser(ctx context.Context, user model.User) (model.User, error) {
+	current, err := u.GetCurrentUser(ctx)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if len(user.Email) > 0 {
+		email, err := normalizeEmail(user.Email)
+		if err != nil {
+			return model.User{}, err
+		}
+		current.Email = email
+	}
+
+	if len(strings.TrimSpace(user.Name)) > 0 {
+		current.Name = strings.TrimSpace(user.Name)
+	}
+
+	if timezone := strings.TrimSpace(user.Timezone); len(timezone) > 0 {
+		if err = u.validateTimezone(ctx, timezone); err != nil {
+			return model.User{}, err
+		}
+		current.Timezone = timezone
+	}
+
+	updated, err := u.UserRepository.UpdateUser(ctx, current)
+	if err != nil {
+		return model.User{}, err
+	}
+	return withoutPassword(updated), nil
+}
+
+// verifyPassword checks password against the stored hash and upgrades the hash when its parameters are outdated.
+func (u User) verifyPassword(ctx context.Context, user model.User, password string) bool {
+	if user.Password == nil {
+		return false
+	}
+
+	match, needsRehash := util.CheckPassword(*user.Password, password, u.PasswordCost)
+	if !match {
+		return false
+	}
+
+	if needsRehash {
+		hash, err := util.HashPassword(password, u.PasswordCost)
+		if err == nil {
+			err = u.UserRepository.RehashPassword(ctx, *user.Id, hash)
+		}
+		if err != nil {
+			util.Logger(ctx).Warnf("Failed to rehash password of user (%v): %v", *user.Id, err)
+		}
+	}
+
+	return true
+}
+
+func (u User) ChangePassword(ctx context.Context, passwordChange model.PasswordChange) error {
+	userId := util.GetUserFromContext(ctx)
+	if userId == nil {
+		return util.MakeError(util.FORBIDDEN, "Forbidden")
+	}
+	current, err := u.UserRepository.GetUserById(ctx, *userId)
+	if err != nil {
+		return err
+	}
+
+	if !u.verifyPassword(ctx, current, passwordChange.CurrentPassword) {
+		return util.MakeError(util.FORBIDDEN, "current password does not match")
+	}
+
+	return u.setPassword(ctx, *current.Id, passwordChange.NewPassword)
+}
+
+// setPassword signs the user out of every session, including the one that changed the password.
+func (u User) setPassword(ctx context.Context, userId int64, password string) error {
+	if err := validatePassword(&password); err != nil {
+		return err
+	}
+
+	hash, err := util.HashPassword(password, u.PasswordCost)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	return u.UserRepository.UpdatePassword(ctx, userId, hash)
+}
+
+func (u User) CheckCredentials(ctx context.Context, credentials model.Credentials) (model.User, error) {
+	user, err := u.UserRepository.GetUserByEmail(ctx, strings.TrimSpace(credentials.Email))
+	if err != nil {
+		var mkError *util.MarketListError
+		if errors.As(err, &mkError) && mkError.ErrorType == util.NOT_FOUND {
+			return model.User{}, util.MakeError(util.UNAUTHORIZED, "invalid email or password")
+		}
+		return model.User{}, err
+	}
+
+	if !u.verifyPassword(ctx, user, credentials.Password) {
+		return model.User{}, util.MakeError(util.UNAUTHORIZED, "invalid email or password")
+	}
+
+	return withoutPassword(user), nil
+}
+
+func (u User) RequestPasswordReset(ctx context.Context, request model.PasswordResetRequest) error {
+	user, err := u.UserRepository.GetUserByEmail(ctx, strings.TrimSpace(request.Email))
+	if err != nil {
+		var mkError *util.MarketListError
+		if errors.As(err, &mkError) && mkError.ErrorType == util.NOT_FOUND {
+			return nil
+		}
+		return err
+	}
+
+	token, err := util.GenerateSecureToken()
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+	expiresAt := time.Now().Add(u.ResetExpiration)
+
+	err = u.UserRepository.CreatePasswordResetToken(ctx, *user.Id, util.HashToken(token), expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return u.PasswordResetSender.SendPasswordReset(ctx, withoutPassword(user), token, expiresAt)
+}
+
+func (u User) ConfirmPasswordReset(ctx context.Context, confirm model.PasswordResetConfirm) error {
+	if err := validatePassword(&confirm.NewPassword); err != nil {
+		return err
+	}
+
+	hash, err := util.HashPassword(confirm.NewPassword, u.PasswordCost)
+	if err != nil {
+		return util.MakeErrorUnknown(err)
+	}
+
+	userId, err := u.UserRepository.ResetPassword(ctx, util.HashToken(confirm.Token), hash)
+	if err != nil {
+		return err
+	}
+
+	util.Logger(ctx).Infof("Reset password of user (%v)", userId)
+
+	return nil
 }
@@ -15,9 +15,9 @@ func Logger(ctx context.Context) echo.Logger {
 }
 
 func GetUserFromContext(ctx context.Context) *int64 {
-	id := ctx.Value("USER_ID").(*int64)
+	id, ok := ctx.Value("USER_ID").(*int64)
 
-	if id != nil {
+	if ok && id != nil {
 		return id
 	}
 
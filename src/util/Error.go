@@ -8,11 +8,13 @@ import (
 type ErrorType string
 
 const (
-	NOT_FOUND      ErrorType = "NOT_FOUND"
-	ALREADY_EXISTS           = "ALREADY_EXISTS"
-	UNKNOWN_ERROR            = "UNKNOWN_ERROR"
-	INVALID_INPUT            = "INVALID_INPUT"
-	FORBIDDEN                = "FORBIDDEN"
+	NOT_FOUND           ErrorType = "NOT_FOUND"
+	ALREADY_EXISTS                = "ALREADY_EXISTS"
+	UNKNOWN_ERROR                 = "UNKNOWN_ERROR"
+	INVALID_INPUT                 = "INVALID_INPUT"
+	FORBIDDEN                     = "FORBIDDEN"
+	UNAUTHORIZED                  = "UNAUTHORIZED"
+	PRECONDITION_FAILED           = "PRECONDITION_FAILED"
 )
 
 func MakeError(errorType ErrorType, message string) *MarketListError {
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func HashPassword(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword compares password against the stored hash. needsRehash is true when the hash was generated
+// with a different cost or when the stored value is a legacy plain text password.
+func CheckPassword(hash, password string, cost int) (match bool, needsRehash bool) {
+	if len(hash) == 0 {
+		return false, false
+	}
+
+	hashCost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		match = subtle.ConstantTimeCompare([]byte(hash), []byte(password)) == 1
+		return match, match
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false, false
+	}
+	return true, hashCost != cost
+}
+
+func GenerateSecureToken() (string, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,74 @@
+package util
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse", bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		hash        string
+		password    string
+		cost        int
+		match       bool
+		needsRehash bool
+	}{
+		{name: "matching password", hash: hash, password: "correct horse", cost: bcrypt.MinCost, match: true},
+		{name: "wrong password", hash: hash, password: "battery staple", cost: bcrypt.MinCost},
+		{name: "outdated cost", hash: hash, password: "correct horse", cost: bcrypt.MinCost + 1, match: true, needsRehash: true},
+		{name: "wrong password with outdated cost", hash: hash, password: "battery staple", cost: bcrypt.MinCost + 1},
+		{name: "legacy plain text password", hash: "correct horse", password: "correct horse", cost: bcrypt.MinCost, match: true, needsRehash: true},
+		{name: "wrong legacy plain text password", hash: "correct horse", password: "battery staple", cost: bcrypt.MinCost},
+		{name: "plain text password does not match its hash", hash: hash, password: hash, cost: bcrypt.MinCost},
+		{name: "empty hash", hash: "", password: "", cost: bcrypt.MinCost},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match, needsRehash := CheckPassword(test.hash, test.password, test.cost)
+			if match != test.match {
+				t.Errorf("match = %v, want %v", match, test.match)
+			}
+			if needsRehash != test.needsRehash {
+				t.Errorf("needsRehash = %v, want %v", needsRehash, test.needsRehash)
+			}
+		})
+	}
+}
+
+func TestGenerateSecureToken(t *testing.T) {
+	first, err := GenerateSecureToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenerateSecureToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != 43 {
+		t.Errorf("token length = %d, want 43", len(first))
+	}
+	if first == second {
+		t.Errorf("tokens are equal: %s", first)
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	hash := HashToken("token")
+	if len(hash) != 64 {
+		t.Errorf("hash length = %d, want 64", len(hash))
+	}
+	if hash != HashToken("token") {
+		t.Errorf("hash is not deterministic")
+	}
+	if hash == HashToken("other token") {
+		t.Errorf("different tokens have the same hash")
+	}
+}